@@ -3,9 +3,9 @@ package es
 import (
 	"context"
 	"fmt"
+	"iter"
 	"reflect"
 
-	"github.com/fgrzl/timestamp"
 	"github.com/google/uuid"
 )
 
@@ -96,6 +96,8 @@ type Aggregate interface {
 	Raise(DomainEvent) error
 	Audit(DomainEvent) error
 	Load([]DomainEvent) error
+	// LoadStream replays committed events as they are read, stopping at the first error.
+	LoadStream(iter.Seq2[DomainEvent, error]) error
 	Commit()
 
 	// GetPendingAudits returns a copy of staged audit events (metadata is applied on Repository.Save).
@@ -117,25 +119,60 @@ type PendingAudit struct {
 	Timestamp int64
 }
 
+// AggregateOption configures an aggregate created by NewAggregate or NewTenantAggregate.
+type AggregateOption func(*aggregateOptions)
+
+type aggregateOptions struct {
+	clock          Clock
+	ids            IDGenerator
+	causationChain bool
+}
+
+// WithClock sets the clock used to stamp event and audit timestamps.
+// It takes precedence over a clock attached with ContextWithClock.
+func WithClock(clock Clock) AggregateOption {
+	return func(o *aggregateOptions) {
+		o.clock = clock
+	}
+}
+
+// WithIDGenerator sets the generator used for event IDs, and through them audit
+// batch stream IDs, and for fallback correlation and causation IDs. It takes precedence over a generator
+// attached with ContextWithIDGenerator.
+func WithIDGenerator(ids IDGenerator) AggregateOption {
+	return func(o *aggregateOptions) {
+		o.ids = ids
+	}
+}
+
+// WithCausationChain makes causation IDs follow the event chain: an event raised by a
+// handler while another event is being raised gets that event's ID as its causation.
+// Events raised directly by command methods keep the aggregate's causation ID.
+func WithCausationChain() AggregateOption {
+	return func(o *aggregateOptions) {
+		o.causationChain = true
+	}
+}
+
 // NewAggregate creates a new global-scoped aggregate with the specified area and ID.
 // It panics when the aggregate definition is invalid, such as when the ID is nil
 // or the area is empty.
-func NewAggregate(ctx context.Context, area string, id uuid.UUID) Aggregate {
-	return newAggregate(ctx, ScopeGlobal, area, uuid.Nil, id)
+func NewAggregate(ctx context.Context, area string, id uuid.UUID, opts ...AggregateOption) Aggregate {
+	return newAggregate(ctx, ScopeGlobal, area, uuid.Nil, id, opts)
 }
 
 // NewTenantAggregate creates a new tenant-scoped aggregate with the specified area, tenant ID, and aggregate ID.
 // It panics when the aggregate definition is invalid, such as when the tenant ID
 // or aggregate ID is nil or the area is empty.
-func NewTenantAggregate(ctx context.Context, area string, tenantID, id uuid.UUID) Aggregate {
+func NewTenantAggregate(ctx context.Context, area string, tenantID, id uuid.UUID, opts ...AggregateOption) Aggregate {
 	if tenantID == uuid.Nil {
 		panic(errNewTenantAggregateNilTenantID)
 	}
 
-	return newAggregate(ctx, ScopeTenant, area, tenantID, id)
+	return newAggregate(ctx, ScopeTenant, area, tenantID, id, opts)
 }
 
-func newAggregate(ctx context.Context, scope Scope, area string, tenantID, id uuid.UUID) Aggregate {
+func newAggregate(ctx context.Context, scope Scope, area string, tenantID, id uuid.UUID, opts []AggregateOption) Aggregate {
 	if id == uuid.Nil {
 		panic(errNewAggregateNilID)
 	}
@@ -154,33 +191,61 @@ func newAggregate(ctx context.Context, scope Scope, area string, tenantID, id uu
 		ctx = context.Background()
 	}
 
+	options := aggregateOptions{
+		clock: GetClock(ctx),
+		ids:   GetIDGenerator(ctx),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.clock == nil {
+		options.clock = systemClock{}
+	}
+	if options.ids == nil {
+		options.ids = randomIDGenerator{}
+	}
+
 	correlationID := GetCorrelationID(ctx)
 	if correlationID == uuid.Nil {
-		correlationID = uuid.New()
+		correlationID = options.ids.NewID()
 	}
 	causationID := GetCausationID(ctx)
 	if causationID == uuid.Nil {
-		causationID = uuid.New()
+		causationID = options.ids.NewID()
 	}
 
 	return &aggregateBase{
-		entity:        entity,
-		correlationID: correlationID,
-		causationID:   causationID,
-		handlers:      make(map[string]DomainEventHandler),
+		entity:         entity,
+		correlationID:  correlationID,
+		causationID:    causationID,
+		causationChain: options.causationChain,
+		clock:          options.clock,
+		ids:            options.ids,
+		handlers:       make(map[string]DomainEventHandler),
 	}
 }
 
 // aggregateBase provides event-sourcing behavior for aggregate implementations.
 // It should be embedded in concrete aggregate types to inherit event sourcing capabilities.
 type aggregateBase struct {
-	entity        Entity
-	correlationID uuid.UUID
-	causationID   uuid.UUID
+	entity         Entity
+	correlationID  uuid.UUID
+	causationID    uuid.UUID
+	causationChain bool
+	// raising holds the IDs of the events whose handlers are running in Raise,
+	// innermost last, when causationChain is set.
+	raising []uuid.UUID
+	// replaying is set while Load or LoadStream runs the handlers of committed events.
+	replaying     bool
+	clock         Clock
+	ids           IDGenerator
 	committed     []DomainEvent
 	uncommitted   []DomainEvent
 	pendingAudits []PendingAudit
 	handlers      map[string]DomainEventHandler
+	// head is the sequence of the last committed event. It is set from loaded
+	// events rather than counted, because a truncated stream does not start at 1.
+	head uint64
 }
 
 func (a *aggregateBase) GetEntity() Entity {
@@ -208,8 +273,15 @@ func (a *aggregateBase) GetCausationID() uuid.UUID {
 	return a.causationID
 }
 
+// AppendCommitted appends a committed event and moves the committed sequence to
+// its sequence. Events without a sequence advance it by one.
 func (a *aggregateBase) AppendCommitted(event DomainEvent) {
 	a.committed = append(a.committed, event)
+	if sequence := event.GetSequence(); sequence > 0 {
+		a.head = sequence
+	} else {
+		a.head++
+	}
 }
 
 func (a *aggregateBase) AppendUncommitted(event DomainEvent) {
@@ -220,8 +292,11 @@ func (a *aggregateBase) GetCommittedEvents() []DomainEvent {
 	return a.committed
 }
 
+// GetCommittedSequence returns the sequence of the last committed event, as loaded
+// from the store or advanced by Commit. After loading a truncated stream it exceeds
+// the number of committed events, so appends still line up with the stream head.
 func (a *aggregateBase) GetCommittedSequence() uint64 {
-	return uint64(len(a.committed))
+	return a.head
 }
 
 func (a *aggregateBase) GetUncommittedEvents() []DomainEvent {
@@ -229,10 +304,11 @@ func (a *aggregateBase) GetUncommittedEvents() []DomainEvent {
 }
 
 func (a *aggregateBase) GetUncommittedSequence() uint64 {
-	return uint64(len(a.committed) + len(a.uncommitted))
+	return a.GetCommittedSequence() + uint64(len(a.uncommitted))
 }
 
 func (a *aggregateBase) Commit() {
+	a.head = a.GetUncommittedSequence()
 	a.committed = append(a.committed, a.uncommitted...)
 	a.uncommitted = make([]DomainEvent, 0)
 }
@@ -278,23 +354,45 @@ func (a *aggregateBase) RegisterHandler(discriminator string, handler DomainEven
 // calling Raise. The default aggregate implementation panics when the event
 // definition is not valid for the aggregate because invalid event-area mappings
 // are treated as design-time wiring errors.
+//
+// Raise does nothing when called from a handler while Load or LoadStream replays
+// committed events: events raised in reaction were committed with the event that
+// caused them and are replayed from the stream in their own right.
 func (a *aggregateBase) Raise(event DomainEvent) error {
 	domainArea := a.entity.Area
 	if !eventListsArea(event, domainArea) {
 		panic(fmt.Sprintf(errRaiseInvalidAggregateArea, domainArea, event))
 	}
+	if a.replaying {
+		return nil
+	}
 
+	causationID := a.GetCausationID()
+	if len(a.raising) > 0 {
+		causationID = a.raising[len(a.raising)-1]
+	}
+	eventID := a.ids.NewID()
 	event.SetMetadata(EventMetadata{
 		Entity:        a.GetEntity(),
-		EventID:       uuid.New(),
+		EventID:       eventID,
 		CorrelationID: a.GetCorrelationID(),
-		CausationID:   a.GetCausationID(),
-		Timestamp:     timestamp.GetTimestamp(),
+		CausationID:   causationID,
+		Timestamp:     a.clock.GetTimestamp(),
 		Sequence:      a.GetUncommittedSequence() + 1,
 	})
 
-	a.applyEvent(event)
+	if !a.causationChain {
+		a.applyEvent(event)
+		a.AppendUncommitted(event)
+		return nil
+	}
+
+	// With a causation chain the event is appended before its handler runs, so
+	// events raised by the handler follow it in sequence as well as in causation.
 	a.AppendUncommitted(event)
+	a.raising = append(a.raising, eventID)
+	defer func() { a.raising = a.raising[:len(a.raising)-1] }()
+	a.applyEvent(event)
 	return nil
 }
 
@@ -312,15 +410,18 @@ func (a *aggregateBase) Audit(event DomainEvent) error {
 		panic("Audit: event instance must not be staged more than once")
 	}
 
-	auditEntity := AuditStreamEntity(a.entity)
+	eventID := a.ids.NewID()
+	var auditEntity Entity
 	if len(a.pendingAudits) > 0 {
 		auditEntity = a.pendingAudits[0].Entity
+	} else {
+		auditEntity = AuditStreamEntityFor(a.entity, eventID)
 	}
 	a.pendingAudits = append(a.pendingAudits, PendingAudit{
 		Event:     event,
 		Entity:    auditEntity,
-		EventID:   uuid.New(),
-		Timestamp: timestamp.GetTimestamp(),
+		EventID:   eventID,
+		Timestamp: a.clock.GetTimestamp(),
 	})
 	return nil
 }
@@ -366,8 +467,28 @@ func eventAlreadyStaged(pending []PendingAudit, event DomainEvent) bool {
 // Load replays committed events onto an aggregate
 func (a *aggregateBase) Load(events []DomainEvent) error {
 	for _, event := range events {
-		a.applyEvent(event)
-		a.AppendCommitted(event)
+		a.replay(event)
 	}
 	return nil
 }
+
+// LoadStream replays committed events onto an aggregate as they are yielded.
+// Events applied before an error remain committed on the aggregate.
+func (a *aggregateBase) LoadStream(events iter.Seq2[DomainEvent, error]) error {
+	for event, err := range events {
+		if err != nil {
+			return err
+		}
+		a.replay(event)
+	}
+	return nil
+}
+
+// replay applies a committed event with Raise suppressed and appends it to the
+// committed events.
+func (a *aggregateBase) replay(event DomainEvent) {
+	a.replaying = true
+	defer func() { a.replaying = false }()
+	a.applyEvent(event)
+	a.AppendCommitted(event)
+}
@@ -2,6 +2,7 @@ package es
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/fgrzl/timestamp"
@@ -212,6 +213,86 @@ func TestShouldReuseAuditBatchUntilDiscarded(t *testing.T) {
 	assert.Equal(t, dummy.GetEntity().Area, next[0].Entity.Area)
 }
 
+func TestShouldStampDeterministicMetadataWhenClockAndIDGeneratorConfigured(t *testing.T) {
+	// Arrange
+	ctx := ContextWithTracing(context.Background(), SequentialID(100), SequentialID(200))
+	clock := NewFakeClock(1000)
+	id := uuid.New()
+	dummy := &Dummy{Aggregate: NewAggregate(ctx, AreaDummy, id, WithClock(clock), WithIDGenerator(NewSequentialIDGenerator()))}
+	RegisterHandler(dummy, dummy.OnDummyCreated)
+
+	// Act
+	err := dummy.Create("test")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, EventMetadata{
+		Entity:        NewEntity(id, AreaDummy),
+		EventID:       SequentialID(1),
+		CorrelationID: SequentialID(100),
+		CausationID:   SequentialID(200),
+		Timestamp:     1000,
+		Sequence:      1,
+	}, dummy.GetUncommittedEvents()[0].GetMetadata())
+}
+
+func TestShouldStampDeterministicAuditWhenClockAndIDGeneratorInContext(t *testing.T) {
+	// Arrange
+	ctx := ContextWithClock(context.Background(), NewFakeClock(42))
+	ctx = ContextWithIDGenerator(ctx, NewSequentialIDGenerator())
+	dummy := &Dummy{Aggregate: NewAggregate(ctx, AreaDummy, uuid.New())}
+
+	// Act
+	err := dummy.LogAudit("login")
+
+	// Assert
+	assert.NoError(t, err)
+	pending := dummy.GetPendingAudits()
+	assert.Len(t, pending, 1)
+	assert.Equal(t, SequentialID(1), dummy.GetCorrelationID())
+	assert.Equal(t, SequentialID(2), dummy.GetCausationID())
+	assert.Equal(t, SequentialID(3), pending[0].EventID)
+	assert.Equal(t, AuditStreamEntityFor(dummy.GetEntity(), SequentialID(3)), pending[0].Entity)
+	assert.Equal(t, int64(42), pending[0].Timestamp)
+}
+
+func TestShouldPreferOptionsOverContextClock(t *testing.T) {
+	// Arrange
+	ctx := ContextWithClock(context.Background(), NewFakeClock(1))
+	dummy := &Dummy{Aggregate: NewAggregate(ctx, AreaDummy, uuid.New(), WithClock(NewFakeClock(2)))}
+	RegisterHandler(dummy, dummy.OnDummyCreated)
+
+	// Act
+	err := dummy.Create("test")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), dummy.GetUncommittedEvents()[0].GetTimestamp())
+}
+
+func TestShouldStopLoadStreamAtFirstError(t *testing.T) {
+	// Arrange
+	dummy := NewDummy()
+	streamErr := errors.New("read failed")
+	events := func(yield func(DomainEvent, error) bool) {
+		if !yield(&DummyCreated{Name: "first"}, nil) {
+			return
+		}
+		if !yield(nil, streamErr) {
+			return
+		}
+		yield(&DummyCreated{Name: "unreachable"}, nil)
+	}
+
+	// Act
+	err := dummy.LoadStream(events)
+
+	// Assert
+	assert.ErrorIs(t, err, streamErr)
+	assert.Equal(t, "first", dummy.name)
+	assert.Len(t, dummy.GetCommittedEvents(), 1)
+}
+
 type DummyCreated struct {
 	DomainEventBase
 	Name string
@@ -281,3 +362,100 @@ func (a *Dummy) LogAudit(reason string) error {
 func (a *Dummy) OnDummyCreated(e *DummyCreated) {
 	a.name = e.Name
 }
+
+func newReactingAggregate(ctx context.Context, opts ...AggregateOption) Aggregate {
+	a := NewAggregate(ctx, AreaDummy, uuid.New(), opts...)
+	RegisterHandler(a, func(*DummyCreated) {
+		_ = a.Raise(&OrderPlaced{})
+	})
+	return a
+}
+
+func TestShouldChainCausationToTriggeringEvent(t *testing.T) {
+	// Arrange
+	causationID := uuid.New()
+	ctx := ContextWithTracing(context.Background(), uuid.New(), causationID)
+	a := newReactingAggregate(ctx, WithCausationChain())
+
+	// Act
+	err := a.Raise(&DummyCreated{Name: "test"})
+
+	// Assert
+	assert.NoError(t, err)
+	events := a.GetUncommittedEvents()
+	assert.Len(t, events, 2)
+	assert.Equal(t, causationID, events[0].GetCausationID())
+	assert.Equal(t, events[0].GetEventID(), events[1].GetCausationID())
+	assert.Equal(t, []uint64{1, 2}, sequencesOf(events))
+}
+
+func TestShouldShareAggregateCausationWithoutCausationChain(t *testing.T) {
+	// Arrange
+	causationID := uuid.New()
+	ctx := ContextWithTracing(context.Background(), uuid.New(), causationID)
+	a := newReactingAggregate(ctx)
+
+	// Act
+	err := a.Raise(&DummyCreated{Name: "test"})
+
+	// Assert
+	assert.NoError(t, err)
+	events := a.GetUncommittedEvents()
+	assert.Len(t, events, 2)
+	assert.Equal(t, causationID, events[0].GetCausationID())
+	assert.Equal(t, causationID, events[1].GetCausationID())
+}
+
+func TestShouldNotRaiseReactionsWhenReplayingCommittedEvents(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repository := NewRepository(NewInMemoryEventStore())
+	a := newReactingAggregate(ctx, WithCausationChain())
+	assert.NoError(t, a.Raise(&DummyCreated{Name: "test"}))
+	assert.NoError(t, repository.Save(ctx, a))
+	loaded := NewAggregate(ctx, AreaDummy, a.GetAggregateID(), WithCausationChain())
+	RegisterHandler(loaded, func(*DummyCreated) {
+		_ = loaded.Raise(&OrderPlaced{})
+	})
+
+	// Act
+	err := repository.Load(ctx, loaded)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, loaded.GetCommittedEvents(), 2)
+	assert.Empty(t, loaded.GetUncommittedEvents())
+	assert.NoError(t, repository.Save(ctx, loaded))
+	assert.Len(t, loaded.GetCommittedEvents(), 2)
+}
+
+func TestShouldLeaveRaisedEventOutOfUncommittedWhenHandlerPanics(t *testing.T) {
+	// Arrange
+	a := NewAggregate(context.Background(), AreaDummy, uuid.New())
+	RegisterHandler(a, func(*DummyCreated) {
+		panic("handler failed")
+	})
+
+	// Act
+	assert.Panics(t, func() { _ = a.Raise(&DummyCreated{Name: "test"}) })
+
+	// Assert
+	assert.Empty(t, a.GetUncommittedEvents())
+}
+
+func TestShouldUseEventIDAsCausationWhenReactingToEvent(t *testing.T) {
+	// Arrange
+	trigger := newTriggerEvent(&OrderPlaced{}, uuid.New())
+	ctx := WithEventMetadata(context.Background(), trigger)
+	dummy := &Dummy{Aggregate: NewAggregate(ctx, AreaDummy, uuid.New())}
+	RegisterHandler(dummy, dummy.OnDummyCreated)
+
+	// Act
+	err := dummy.Create("reaction")
+
+	// Assert
+	assert.NoError(t, err)
+	event := dummy.GetUncommittedEvents()[0]
+	assert.Equal(t, trigger.GetCorrelationID(), event.GetCorrelationID())
+	assert.Equal(t, trigger.GetEventID(), event.GetCausationID())
+}
@@ -0,0 +1,280 @@
+package es
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultRepositoryCacheSize is the number of streams a CachingRepository keeps
+// when WithCacheSize is not given.
+const defaultRepositoryCacheSize = 1024
+
+// CachingRepositoryOption configures a CachingRepository.
+type CachingRepositoryOption func(*CachingRepository)
+
+// WithCacheSize sets how many streams a CachingRepository keeps before evicting the
+// least recently used one.
+func WithCacheSize(size int) CachingRepositoryOption {
+	return func(r *CachingRepository) {
+		r.size = max(size, 1)
+	}
+}
+
+// RepositoryCacheStats reports the effectiveness of a CachingRepository.
+type RepositoryCacheStats struct {
+	// Hits counts loads served from the cache plus a catch-up read.
+	Hits uint64
+	// Misses counts loads that read the whole stream.
+	Misses uint64
+	// Evictions counts streams dropped for space, conflicts, or deletions.
+	Evictions uint64
+	// Entries is the number of streams currently cached.
+	Entries int
+}
+
+// CachingRepository is a Repository that keeps the committed events of recently
+// loaded aggregates in an LRU cache keyed by Entity. Loads go through the wrapped
+// Repository, whose store view serves a cached stream from memory plus a catch-up
+// read of the events appended since, from the cached head sequence + 1.
+//
+// Streams with MaxCount or MaxAge retention, as reported by a StreamMetadataStore,
+// are never served from the cache, so cached and uncached loads see the same
+// events. A stream is evicted when a save fails with ErrConcurrency, when the
+// catch-up read fails, as for a stream soft deleted elsewhere, and when it is
+// deleted or truncated through this repository. Hard deletions and truncations
+// made elsewhere are not observed, so make them through this repository.
+type CachingRepository struct {
+	Repository
+	store Store
+	size  int
+
+	mu      sync.Mutex
+	entries map[Entity]*list.Element
+	lru     *list.List
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type repositoryCacheEntry struct {
+	entity Entity
+	events []DomainEvent
+	head   uint64
+}
+
+// NewCachingRepository creates a caching repository over store.
+func NewCachingRepository(store Store, opts ...CachingRepositoryOption) *CachingRepository {
+	r := &CachingRepository{
+		store:   store,
+		size:    defaultRepositoryCacheSize,
+		entries: make(map[Entity]*list.Element),
+		lru:     list.New(),
+	}
+	r.Repository = NewRepository(cachedStreamStore{ForwardingStore: ForwardingStore{Next: store}, cache: r})
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// cachedStreamStore is the store of a CachingRepository's wrapped Repository. It
+// serves whole-stream reads through the cache and forwards everything else.
+type cachedStreamStore struct {
+	ForwardingStore
+	cache *CachingRepository
+}
+
+// LoadEventStream implements StreamingStore.LoadEventStream.
+func (s cachedStreamStore) LoadEventStream(ctx context.Context, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error] {
+	if minSequence > 1 {
+		return StreamEvents(ctx, s.Next, entity, minSequence)
+	}
+	return s.cache.loadStream(ctx, entity)
+}
+
+// loadStream yields an entity's stream from the cache and the store, and caches
+// the stream once it has been read to the end.
+func (r *CachingRepository) loadStream(ctx context.Context, entity Entity) iter.Seq2[DomainEvent, error] {
+	return func(yield func(DomainEvent, error) bool) {
+		retained, err := r.retained(ctx, entity)
+		if err != nil {
+			r.Evict(entity)
+			yield(nil, err)
+			return
+		}
+
+		var cached []DomainEvent
+		var head uint64
+		hit := false
+		if retained {
+			r.Evict(entity)
+		} else {
+			cached, head, hit = r.lookup(entity)
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool(attributeCacheHit, hit))
+		if !hit {
+			r.misses.Add(1)
+			head = 0
+		} else {
+			r.hits.Add(1)
+			for _, event := range cached {
+				if !yield(event, nil) {
+					return
+				}
+			}
+		}
+
+		events := cached[:len(cached):len(cached)]
+		for event, err := range StreamEvents(ctx, r.store, entity, head+1) {
+			if err != nil {
+				if hit {
+					r.Evict(entity)
+				}
+				yield(nil, err)
+				return
+			}
+			events = append(events, event)
+			if !yield(event, nil) {
+				return
+			}
+		}
+		if !retained && len(events) > len(cached) {
+			r.put(entity, events, events[len(events)-1].GetSequence())
+		}
+	}
+}
+
+// retained reports whether the store applies MaxCount or MaxAge retention to the
+// stream, so the stream must not be served from the cache.
+func (r *CachingRepository) retained(ctx context.Context, entity Entity) (bool, error) {
+	metadataStore, ok := r.store.(StreamMetadataStore)
+	if !ok {
+		return false, nil
+	}
+	metadata, found, err := metadataStore.GetStreamMetadata(ctx, entity)
+	if err != nil {
+		return false, err
+	}
+	return found && (metadata.Settings.MaxCount > 0 || metadata.Settings.MaxAge > 0), nil
+}
+
+// Save implements Repository.Save and adds the saved events to the cached stream.
+// A conflict evicts the stream so the next load reads it from the store.
+func (r *CachingRepository) Save(ctx context.Context, a Aggregate) error {
+	entity := a.GetEntity()
+	expectedSequence := a.GetCommittedSequence()
+	uncommitted := slices.Clone(a.GetUncommittedEvents())
+
+	if err := r.Repository.Save(ctx, a); err != nil {
+		if errors.Is(err, ErrConcurrency) {
+			r.Evict(entity)
+		}
+		return err
+	}
+	if len(uncommitted) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if element, ok := r.entries[entity]; ok {
+		entry := element.Value.(*repositoryCacheEntry)
+		if entry.head == expectedSequence {
+			entry.events = append(entry.events[:len(entry.events):len(entry.events)], uncommitted...)
+			entry.head = a.GetCommittedSequence()
+			r.lru.MoveToFront(element)
+			return nil
+		}
+	}
+	r.putLocked(entity, slices.Clone(a.GetCommittedEvents()), a.GetCommittedSequence())
+	return nil
+}
+
+// SoftDelete implements Repository.SoftDelete and evicts the stream.
+func (r *CachingRepository) SoftDelete(ctx context.Context, entity Entity) error {
+	r.Evict(entity)
+	return r.Repository.SoftDelete(ctx, entity)
+}
+
+// HardDelete implements Repository.HardDelete and evicts the stream.
+func (r *CachingRepository) HardDelete(ctx context.Context, entity Entity) error {
+	r.Evict(entity)
+	return r.Repository.HardDelete(ctx, entity)
+}
+
+// TruncateBefore implements Repository.TruncateBefore and evicts the stream.
+func (r *CachingRepository) TruncateBefore(ctx context.Context, entity Entity, sequence uint64) error {
+	r.Evict(entity)
+	return r.Repository.TruncateBefore(ctx, entity, sequence)
+}
+
+// Evict drops an entity's stream from the cache.
+func (r *CachingRepository) Evict(entity Entity) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if element, ok := r.entries[entity]; ok {
+		r.removeLocked(element)
+	}
+}
+
+// Stats returns the cache counters.
+func (r *CachingRepository) Stats() RepositoryCacheStats {
+	r.mu.Lock()
+	entries := r.lru.Len()
+	r.mu.Unlock()
+	return RepositoryCacheStats{
+		Hits:      r.hits.Load(),
+		Misses:    r.misses.Load(),
+		Evictions: r.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+func (r *CachingRepository) lookup(entity Entity) ([]DomainEvent, uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	element, ok := r.entries[entity]
+	if !ok {
+		return nil, 0, false
+	}
+	r.lru.MoveToFront(element)
+	entry := element.Value.(*repositoryCacheEntry)
+	return entry.events, entry.head, true
+}
+
+func (r *CachingRepository) put(entity Entity, events []DomainEvent, head uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.putLocked(entity, events, head)
+}
+
+// putLocked caches events unless a newer version of the stream is already cached.
+func (r *CachingRepository) putLocked(entity Entity, events []DomainEvent, head uint64) {
+	if element, ok := r.entries[entity]; ok {
+		entry := element.Value.(*repositoryCacheEntry)
+		if entry.head <= head {
+			entry.events, entry.head = events, head
+		}
+		r.lru.MoveToFront(element)
+		return
+	}
+	r.entries[entity] = r.lru.PushFront(&repositoryCacheEntry{entity: entity, events: events, head: head})
+	for r.lru.Len() > r.size {
+		r.removeLocked(r.lru.Back())
+	}
+}
+
+func (r *CachingRepository) removeLocked(element *list.Element) {
+	r.lru.Remove(element)
+	delete(r.entries, element.Value.(*repositoryCacheEntry).entity)
+	r.evictions.Add(1)
+}
@@ -0,0 +1,188 @@
+package es
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func newDummyWithID(id uuid.UUID) *Dummy {
+	dummy := &Dummy{Aggregate: NewAggregate(context.Background(), AreaDummy, id)}
+	RegisterHandler(dummy, dummy.OnDummyCreated)
+	return dummy
+}
+
+func newRecordedStore() (Store, *[]StoreCall) {
+	calls := &[]StoreCall{}
+	recorder := StoreRecorderFunc(func(_ context.Context, call StoreCall) {
+		*calls = append(*calls, call)
+	})
+	return ChainStore(NewInMemoryEventStore(), RecordingStore(recorder)), calls
+}
+
+func TestShouldCatchUpCachedStreamOnLoad(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store, calls := newRecordedStore()
+	cache := NewCachingRepository(store)
+	other := NewRepository(store)
+	id := uuid.New()
+	first := newDummyWithID(id)
+	require.NoError(t, first.Create("first"))
+	require.NoError(t, cache.Save(ctx, first))
+	stale := newDummyWithID(id)
+	require.NoError(t, other.Load(ctx, stale))
+	require.NoError(t, stale.Create("second"))
+	require.NoError(t, other.Save(ctx, stale))
+	*calls = nil
+
+	// Act
+	loaded := newDummyWithID(id)
+	err := cache.Load(ctx, loaded)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "second", loaded.name)
+	assert.Equal(t, uint64(2), loaded.GetCommittedSequence())
+	require.Len(t, *calls, 1)
+	assert.Equal(t, StoreOperationLoadEventStream, (*calls)[0].Operation)
+	assert.Equal(t, 1, (*calls)[0].Events)
+	assert.Equal(t, RepositoryCacheStats{Hits: 1, Entries: 1}, cache.Stats())
+}
+
+func TestShouldCacheStreamAfterMiss(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	id := uuid.New()
+	saved := newDummyWithID(id)
+	require.NoError(t, saved.Create("saved"))
+	require.NoError(t, NewRepository(store).Save(ctx, saved))
+	cache := NewCachingRepository(store)
+
+	// Act
+	first := newDummyWithID(id)
+	firstErr := cache.Load(ctx, first)
+	second := newDummyWithID(id)
+	secondErr := cache.Load(ctx, second)
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, "saved", second.name)
+	assert.Equal(t, RepositoryCacheStats{Hits: 1, Misses: 1, Entries: 1}, cache.Stats())
+}
+
+func TestShouldEvictCachedStreamOnConcurrencyError(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cache := NewCachingRepository(NewInMemoryEventStore())
+	id := uuid.New()
+	created := newDummyWithID(id)
+	require.NoError(t, created.Create("created"))
+	require.NoError(t, cache.Save(ctx, created))
+	stale := newDummyWithID(id)
+	require.NoError(t, stale.Create("stale"))
+
+	// Act
+	err := cache.Save(ctx, stale)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrConcurrency)
+	assert.Equal(t, RepositoryCacheStats{Evictions: 1}, cache.Stats())
+	reloaded := newDummyWithID(id)
+	require.NoError(t, cache.Load(ctx, reloaded))
+	assert.Equal(t, "created", reloaded.name)
+}
+
+func TestShouldEvictLeastRecentlyUsedStream(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cache := NewCachingRepository(NewInMemoryEventStore(), WithCacheSize(1))
+	first := newDummyWithID(uuid.New())
+	second := newDummyWithID(uuid.New())
+	require.NoError(t, first.Create("first"))
+	require.NoError(t, second.Create("second"))
+
+	// Act
+	require.NoError(t, cache.Save(ctx, first))
+	require.NoError(t, cache.Save(ctx, second))
+	require.NoError(t, cache.Load(ctx, newDummyWithID(first.GetAggregateID())))
+
+	// Assert
+	assert.Equal(t, RepositoryCacheStats{Misses: 1, Evictions: 2, Entries: 1}, cache.Stats())
+}
+
+func TestShouldEvictCachedStreamOnSoftDelete(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cache := NewCachingRepository(NewInMemoryEventStore())
+	dummy := newDummyWithID(uuid.New())
+	require.NoError(t, dummy.Create("created"))
+	require.NoError(t, cache.Save(ctx, dummy))
+
+	// Act
+	require.NoError(t, cache.SoftDelete(ctx, dummy.GetEntity()))
+	err := cache.Load(ctx, newDummyWithID(dummy.GetAggregateID()))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrStreamDeleted)
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestShouldNotServeStreamsWithRetentionFromCache(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	cache := NewCachingRepository(store)
+	dummy := newDummyWithID(uuid.New())
+	for i := range 5 {
+		require.NoError(t, dummy.Create(fmt.Sprintf("name-%d", i+1)))
+	}
+	require.NoError(t, cache.Save(ctx, dummy))
+	require.NoError(t, store.(*InMemoryEventStore).SetStreamSettings(ctx, dummy.GetEntity(), StreamSettings{MaxCount: 2}))
+
+	// Act
+	cached := newDummyWithID(dummy.GetAggregateID())
+	cachedErr := cache.Load(ctx, cached)
+	uncached := newDummyWithID(dummy.GetAggregateID())
+	uncachedErr := NewRepository(store).Load(ctx, uncached)
+
+	// Assert
+	require.NoError(t, cachedErr)
+	require.NoError(t, uncachedErr)
+	assert.Equal(t, uncached.GetCommittedEvents(), cached.GetCommittedEvents())
+	assert.Len(t, cached.GetCommittedEvents(), 2)
+	assert.Equal(t, RepositoryCacheStats{Misses: 1, Evictions: 1}, cache.Stats())
+}
+
+func TestShouldLoadCachedStreamThroughRepositorySpan(t *testing.T) {
+	// Arrange
+	spanRecorder := setupSpanRecorder(t)
+	ctx := context.Background()
+	cache := NewCachingRepository(NewInMemoryEventStore())
+	dummy := newDummyWithID(uuid.New())
+	require.NoError(t, dummy.Create("created"))
+	require.NoError(t, cache.Save(ctx, dummy))
+
+	// Act
+	err := cache.Load(ctx, newDummyWithID(dummy.GetAggregateID()))
+
+	// Assert
+	require.NoError(t, err)
+	var loads []sdktrace.ReadOnlySpan
+	for _, span := range spanRecorder.Ended() {
+		if span.Name() == spanRepositoryLoad {
+			loads = append(loads, span)
+		}
+	}
+	require.Len(t, loads, 1)
+	assert.Contains(t, loads[0].Attributes(), attribute.Bool(attributeCacheHit, true))
+	assertSpanInt64Attribute(t, loads[0], attributeEventsCount, 1)
+}
@@ -0,0 +1,50 @@
+package es
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldAdvanceFakeClock(t *testing.T) {
+	// Arrange
+	clock := NewFakeClock(10)
+
+	// Act
+	advanced := clock.Advance(5)
+
+	// Assert
+	assert.Equal(t, int64(15), advanced)
+	assert.Equal(t, int64(15), clock.GetTimestamp())
+	clock.Set(3)
+	assert.Equal(t, int64(3), clock.GetTimestamp())
+}
+
+func TestShouldGenerateSequentialIDs(t *testing.T) {
+	// Arrange
+	ids := NewSequentialIDGenerator()
+
+	// Act
+	first := ids.NewID()
+	second := ids.NewID()
+
+	// Assert
+	assert.Equal(t, uuid.MustParse("00000000-0000-0000-0000-000000000001"), first)
+	assert.Equal(t, SequentialID(2), second)
+}
+
+func TestShouldFallBackToDefaultsWhenContextHasNoClockOrIDGenerator(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+
+	// Act
+	clock := GetClock(ctx)
+	ids := GetIDGenerator(ctx)
+
+	// Assert
+	assert.IsType(t, systemClock{}, clock)
+	assert.IsType(t, randomIDGenerator{}, ids)
+	assert.NotEqual(t, uuid.Nil, ids.NewID())
+}
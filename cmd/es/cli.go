@@ -0,0 +1,336 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fgrzl/es"
+	"github.com/google/uuid"
+)
+
+const usage = `usage: es <command> [flags]
+
+commands:
+  streams list                        list streams with event counts
+  stream read <area> <id> [--tenant]  read one stream in sequence order
+  events tail [-n 20]                 show the most recent events
+  audit find <area> <id> [--tenant]   find audit events written for a stream
+
+flags:
+  --file path         NDJSON dump file or directory (default $ES_DUMP)
+  --type name         only events with this discriminator
+  --correlation id    only events with this correlation ID
+  --output format     pretty or json (default pretty)
+`
+
+var errUsage = errors.New("invalid usage")
+
+// options holds the flags shared by every command.
+type options struct {
+	file          string
+	discriminator string
+	correlation   string
+	output        string
+	tenant        string
+	count         int
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if err := execute(args, stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(stderr, "es: %v\n\n%s", err, usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "es: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func execute(args []string, stdout io.Writer) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%w: missing command", errUsage)
+	}
+	command := args[0] + " " + args[1]
+
+	opts, positional, err := parseFlags(args[2:])
+	if err != nil {
+		return err
+	}
+	if opts.output != "pretty" && opts.output != "json" {
+		return fmt.Errorf("%w: unknown output %q", errUsage, opts.output)
+	}
+
+	var selected []es.Envelope
+	switch command {
+	case "streams list":
+		envelopes, err := load(opts)
+		if err != nil {
+			return err
+		}
+		return listStreams(stdout, filter(envelopes, opts), opts.output)
+	case "stream read":
+		entity, err := parseEntity(positional, opts.tenant)
+		if err != nil {
+			return err
+		}
+		envelopes, err := load(opts)
+		if err != nil {
+			return err
+		}
+		selected = readStream(filter(envelopes, opts), entity)
+	case "events tail":
+		envelopes, err := load(opts)
+		if err != nil {
+			return err
+		}
+		selected = tail(filter(envelopes, opts), opts.count)
+	case "audit find":
+		entity, err := parseEntity(positional, opts.tenant)
+		if err != nil {
+			return err
+		}
+		envelopes, err := load(opts)
+		if err != nil {
+			return err
+		}
+		selected = filter(findAudits(envelopes, entity), opts)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+	return printEvents(stdout, selected, opts.output)
+}
+
+// parseFlags parses flags that may appear before, between, or after positional
+// arguments.
+func parseFlags(args []string) (options, []string, error) {
+	opts := options{file: os.Getenv("ES_DUMP")}
+	flags := flag.NewFlagSet("es", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&opts.file, "file", opts.file, "")
+	flags.StringVar(&opts.file, "f", opts.file, "")
+	flags.StringVar(&opts.discriminator, "type", "", "")
+	flags.StringVar(&opts.correlation, "correlation", "", "")
+	flags.StringVar(&opts.output, "output", "pretty", "")
+	flags.StringVar(&opts.output, "o", "pretty", "")
+	flags.StringVar(&opts.tenant, "tenant", "", "")
+	flags.IntVar(&opts.count, "n", 20, "")
+
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return options{}, nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return opts, positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func parseEntity(positional []string, tenant string) (es.Entity, error) {
+	if len(positional) != 2 {
+		return es.Entity{}, fmt.Errorf("%w: expected <area> <id>", errUsage)
+	}
+	id, err := uuid.Parse(positional[1])
+	if err != nil {
+		return es.Entity{}, fmt.Errorf("%w: invalid id: %v", errUsage, err)
+	}
+	if tenant == "" {
+		return es.NewEntity(id, positional[0]), nil
+	}
+	tenantID, err := uuid.Parse(tenant)
+	if err != nil {
+		return es.Entity{}, fmt.Errorf("%w: invalid tenant: %v", errUsage, err)
+	}
+	return es.NewTenantEntity(tenantID, id, positional[0]), nil
+}
+
+// load reads every envelope of the dump file, or of each *.ndjson file in a dump
+// directory in name order.
+func load(opts options) ([]es.Envelope, error) {
+	if opts.file == "" {
+		return nil, fmt.Errorf("%w: no dump given; use --file or ES_DUMP", errUsage)
+	}
+	info, err := os.Stat(opts.file)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{opts.file}
+	if info.IsDir() {
+		if paths, err = filepath.Glob(filepath.Join(opts.file, "*.ndjson")); err != nil {
+			return nil, err
+		}
+		slices.Sort(paths)
+	}
+
+	var envelopes []es.Envelope
+	for _, path := range paths {
+		if err := readFile(path, &envelopes); err != nil {
+			return nil, err
+		}
+	}
+	return envelopes, nil
+}
+
+func readFile(path string, envelopes *[]es.Envelope) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for envelope, err := range es.ReadEnvelopes(file) {
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if envelope, err = envelope.Decompress(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		*envelopes = append(*envelopes, envelope)
+	}
+	return nil
+}
+
+func filter(envelopes []es.Envelope, opts options) []es.Envelope {
+	return slices.DeleteFunc(slices.Clone(envelopes), func(e es.Envelope) bool {
+		if opts.discriminator != "" && e.Discriminator != opts.discriminator {
+			return true
+		}
+		return opts.correlation != "" && e.Metadata.CorrelationID.String() != opts.correlation
+	})
+}
+
+func readStream(envelopes []es.Envelope, entity es.Entity) []es.Envelope {
+	stream := slices.DeleteFunc(envelopes, func(e es.Envelope) bool {
+		return e.Metadata.Entity != entity
+	})
+	slices.SortStableFunc(stream, func(a, b es.Envelope) int {
+		return cmp.Compare(a.Metadata.Sequence, b.Metadata.Sequence)
+	})
+	return stream
+}
+
+// tail returns the count most recent events by timestamp, oldest first.
+func tail(envelopes []es.Envelope, count int) []es.Envelope {
+	slices.SortStableFunc(envelopes, func(a, b es.Envelope) int {
+		return cmp.Compare(a.Metadata.Timestamp, b.Metadata.Timestamp)
+	})
+	if count >= 0 && len(envelopes) > count {
+		envelopes = envelopes[len(envelopes)-count:]
+	}
+	return envelopes
+}
+
+// findAudits returns events stored in audit batch streams for a domain stream.
+// A stream is an audit batch of the domain stream when its ID is the one
+// es.AuditStreamEntityFor derives from the domain entity and its first event.
+func findAudits(envelopes []es.Envelope, entity es.Entity) []es.Envelope {
+	batches := make(map[es.Entity]bool)
+	for _, e := range envelopes {
+		if e.Metadata.Sequence == 1 && es.AuditStreamEntityFor(entity, e.Metadata.EventID) == e.Metadata.Entity {
+			batches[e.Metadata.Entity] = true
+		}
+	}
+
+	audits := slices.DeleteFunc(slices.Clone(envelopes), func(e es.Envelope) bool {
+		return !batches[e.Metadata.Entity]
+	})
+	slices.SortStableFunc(audits, func(a, b es.Envelope) int {
+		return cmp.Compare(a.Metadata.Timestamp, b.Metadata.Timestamp)
+	})
+	return audits
+}
+
+type streamSummary struct {
+	Entity        es.Entity `json:"entity"`
+	Events        int       `json:"events"`
+	LastSequence  uint64    `json:"last_sequence"`
+	LastTimestamp int64     `json:"last_timestamp"`
+}
+
+func listStreams(w io.Writer, envelopes []es.Envelope, output string) error {
+	summaries := make(map[es.Entity]*streamSummary)
+	for _, e := range envelopes {
+		summary, ok := summaries[e.Metadata.Entity]
+		if !ok {
+			summary = &streamSummary{Entity: e.Metadata.Entity}
+			summaries[e.Metadata.Entity] = summary
+		}
+		summary.Events++
+		summary.LastSequence = max(summary.LastSequence, e.Metadata.Sequence)
+		summary.LastTimestamp = max(summary.LastTimestamp, e.Metadata.Timestamp)
+	}
+
+	streams := make([]*streamSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		streams = append(streams, summary)
+	}
+	slices.SortFunc(streams, func(a, b *streamSummary) int {
+		return cmp.Or(
+			strings.Compare(a.Entity.Area, b.Entity.Area),
+			strings.Compare(a.Entity.TenantID.String(), b.Entity.TenantID.String()),
+			strings.Compare(a.Entity.ID.String(), b.Entity.ID.String()),
+		)
+	})
+
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		for _, stream := range streams {
+			if err := encoder.Encode(stream); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	fmt.Fprintf(w, "%-16s %-36s %-36s %6s %8s\n", "AREA", "ID", "TENANT", "EVENTS", "LAST_SEQ")
+	for _, stream := range streams {
+		tenant := "-"
+		if stream.Entity.Scope == es.ScopeTenant {
+			tenant = stream.Entity.TenantID.String()
+		}
+		fmt.Fprintf(w, "%-16s %-36s %-36s %6d %8d\n", stream.Entity.Area, stream.Entity.ID, tenant, stream.Events, stream.LastSequence)
+	}
+	return nil
+}
+
+func printEvents(w io.Writer, envelopes []es.Envelope, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		for _, e := range envelopes {
+			if err := encoder.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, e := range envelopes {
+		m := e.Metadata
+		fmt.Fprintf(w, "%s/%s #%d %s ts=%d event=%s correlation=%s causation=%s\n",
+			m.Entity.Area, m.Entity.ID, m.Sequence, e.Discriminator, m.Timestamp, m.EventID, m.CorrelationID, m.CausationID)
+
+		var payload any
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(payload, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  %s\n", data)
+	}
+	return nil
+}
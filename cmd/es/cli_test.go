@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/es"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TicketOpened struct {
+	es.DomainEventBase
+	Title string `json:"title"`
+}
+
+func (e *TicketOpened) GetDiscriminator() string { return "ticket_opened" }
+func (e *TicketOpened) GetAreas() []string       { return []string{"tickets"} }
+func (e *TicketOpened) GetSpaces() []string      { return e.GetAreas() }
+
+type TicketViewed struct {
+	es.DomainEventBase
+}
+
+func (e *TicketViewed) GetDiscriminator() string { return "ticket_viewed" }
+func (e *TicketViewed) GetAreas() []string       { return []string{"tickets"} }
+func (e *TicketViewed) GetSpaces() []string      { return e.GetAreas() }
+
+type dumpFixture struct {
+	path        string
+	ticket      es.Entity
+	other       es.Entity
+	audit       es.Entity
+	correlation uuid.UUID
+	auditEvent  uuid.UUID
+}
+
+func writeDump(t *testing.T) dumpFixture {
+	t.Helper()
+	fixture := dumpFixture{
+		path:        filepath.Join(t.TempDir(), "dump.ndjson"),
+		ticket:      es.NewEntity(uuid.New(), "tickets"),
+		other:       es.NewEntity(uuid.New(), "tickets"),
+		correlation: uuid.New(),
+		auditEvent:  uuid.New(),
+	}
+	fixture.audit = es.AuditStreamEntityFor(fixture.ticket, fixture.auditEvent)
+
+	events := []es.DomainEvent{
+		newEvent(fixture.ticket, uuid.New(), 2, 30, fixture.correlation, &TicketOpened{Title: "second"}),
+		newEvent(fixture.ticket, uuid.New(), 1, 10, fixture.correlation, &TicketOpened{Title: "first"}),
+		newEvent(fixture.audit, fixture.auditEvent, 1, 20, fixture.correlation, &TicketViewed{}),
+		newEvent(fixture.other, uuid.New(), 1, 40, uuid.New(), &TicketOpened{Title: "other"}),
+	}
+	writeEvents(t, fixture.path, events)
+	return fixture
+}
+
+func newEvent(entity es.Entity, id uuid.UUID, sequence uint64, timestamp int64, correlation uuid.UUID, e es.DomainEvent) es.DomainEvent {
+	e.SetMetadata(es.EventMetadata{Entity: entity, EventID: id, CorrelationID: correlation, Sequence: sequence, Timestamp: timestamp})
+	return e
+}
+
+func writeEvents(t *testing.T, path string, events []es.DomainEvent) {
+	t.Helper()
+	var buf bytes.Buffer
+	codec := es.NewJSONCodec()
+	for _, e := range events {
+		data, err := codec.Marshal(e)
+		require.NoError(t, err)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
+func runCLI(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func decodeLines(t *testing.T, output string) []es.Envelope {
+	t.Helper()
+	var envelopes []es.Envelope
+	for envelope, err := range es.ReadEnvelopes(strings.NewReader(output)) {
+		require.NoError(t, err)
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes
+}
+
+func TestShouldListStreams(t *testing.T) {
+	// Arrange
+	fixture := writeDump(t)
+
+	// Act
+	stdout, _, code := runCLI(t, "streams", "list", "--file", fixture.path, "--output", "json")
+
+	// Assert
+	require.Equal(t, 0, code)
+	var streams []streamSummary
+	decoder := json.NewDecoder(strings.NewReader(stdout))
+	for decoder.More() {
+		var stream streamSummary
+		require.NoError(t, decoder.Decode(&stream))
+		streams = append(streams, stream)
+	}
+	require.Len(t, streams, 3)
+	counts := map[es.Entity]int{}
+	for _, stream := range streams {
+		counts[stream.Entity] = stream.Events
+	}
+	assert.Equal(t, 2, counts[fixture.ticket])
+	assert.Equal(t, 1, counts[fixture.audit])
+}
+
+func TestShouldReadStreamInSequenceOrder(t *testing.T) {
+	// Arrange
+	fixture := writeDump(t)
+
+	// Act
+	stdout, _, code := runCLI(t, "stream", "read", "tickets", fixture.ticket.ID.String(), "--file", fixture.path, "-o", "json")
+
+	// Assert
+	require.Equal(t, 0, code)
+	envelopes := decodeLines(t, stdout)
+	require.Len(t, envelopes, 2)
+	assert.Equal(t, uint64(1), envelopes[0].Metadata.Sequence)
+	assert.JSONEq(t, `{"title":"first"}`, string(envelopes[0].Payload))
+}
+
+func TestShouldTailMostRecentEventsWithFilters(t *testing.T) {
+	// Arrange
+	fixture := writeDump(t)
+	t.Setenv("ES_DUMP", fixture.path)
+
+	// Act
+	stdout, _, code := runCLI(t, "events", "tail", "-n", "2", "--type", "ticket_opened", "-o", "json")
+
+	// Assert
+	require.Equal(t, 0, code)
+	envelopes := decodeLines(t, stdout)
+	require.Len(t, envelopes, 2)
+	assert.Equal(t, int64(30), envelopes[0].Metadata.Timestamp)
+	assert.Equal(t, int64(40), envelopes[1].Metadata.Timestamp)
+}
+
+func TestShouldFindAuditEventsWrittenForStream(t *testing.T) {
+	// Arrange
+	fixture := writeDump(t)
+
+	// Act
+	stdout, _, code := runCLI(t, "audit", "find", "tickets", fixture.ticket.ID.String(), "--file", fixture.path)
+
+	// Assert
+	require.Equal(t, 0, code)
+	assert.Contains(t, stdout, "ticket_viewed")
+	assert.Contains(t, stdout, fixture.audit.ID.String())
+	assert.NotContains(t, stdout, "ticket_opened")
+}
+
+func TestShouldNotReportCorrelatedDomainStreamsAsAudits(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "dump.ndjson")
+	ticket := es.NewEntity(uuid.New(), "tickets")
+	sibling := es.NewEntity(uuid.New(), "tickets")
+	correlation := uuid.New()
+	writeEvents(t, path, []es.DomainEvent{
+		newEvent(ticket, uuid.New(), 1, 10, correlation, &TicketOpened{Title: "ticket"}),
+		newEvent(sibling, uuid.New(), 1, 20, correlation, &TicketViewed{}),
+	})
+
+	// Act
+	stdout, _, code := runCLI(t, "audit", "find", "tickets", ticket.ID.String(), "--file", path, "-o", "json")
+
+	// Assert
+	require.Equal(t, 0, code)
+	assert.Empty(t, decodeLines(t, stdout))
+}
+
+func TestShouldPrettyPrintPayloads(t *testing.T) {
+	// Arrange
+	fixture := writeDump(t)
+
+	// Act
+	stdout, _, code := runCLI(t, "stream", "read", "tickets", fixture.ticket.ID.String(), "--file", fixture.path,
+		"--correlation", fixture.correlation.String())
+
+	// Assert
+	require.Equal(t, 0, code)
+	assert.Contains(t, stdout, "tickets/"+fixture.ticket.ID.String()+" #1 ticket_opened")
+	assert.Contains(t, stdout, `"title": "first"`)
+}
+
+func TestShouldReportUsageErrors(t *testing.T) {
+	// Act
+	_, stderr, code := runCLI(t, "stream", "read", "tickets")
+
+	// Assert
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "expected <area> <id>")
+	assert.Contains(t, stderr, "usage: es")
+}
@@ -0,0 +1,21 @@
+// Command es inspects event store dumps: newline-delimited JSON envelopes as
+// written by es.JSONCodec, in one file or a directory of *.ndjson files.
+//
+// Usage:
+//
+//	es streams list                       list streams with event counts
+//	es stream read <area> <id> [--tenant] read one stream in sequence order
+//	es events tail [-n 20]                show the most recent events
+//	es audit find <area> <id> [--tenant]  find audit events written for a stream
+//
+// Every command takes --file (or the ES_DUMP environment variable), --type and
+// --correlation filters, and --output pretty|json.
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
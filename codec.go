@@ -0,0 +1,304 @@
+package es
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// EncodingGzip is the Envelope.Encoding of gzip-compressed payloads.
+	EncodingGzip = "gzip"
+
+	// ContentTypeJSON tags envelopes written by JSONCodec.
+	ContentTypeJSON = "application/json"
+	// ContentTypeMsgPack tags envelopes written by MsgPackCodec.
+	ContentTypeMsgPack = "application/msgpack"
+)
+
+// EventCodec converts events to and from bytes for stores that persist them.
+// Every codec tags its output with its content type so formats can coexist in
+// one store; see MixedCodec.
+type EventCodec interface {
+	// ContentType identifies the codec's format, for example ContentTypeJSON.
+	ContentType() string
+	Marshal(event DomainEvent) ([]byte, error)
+	Unmarshal(data []byte) (DomainEvent, error)
+}
+
+var codecRegistry = struct {
+	sync.RWMutex
+	codecs map[string]EventCodec
+}{codecs: map[string]EventCodec{
+	ContentTypeJSON:    NewJSONCodec(),
+	ContentTypeMsgPack: NewMsgPackCodec(),
+}}
+
+func init() {
+	// Events the package writes to the stores it wraps are registered by default,
+	// so stores that persist bytes can decode them again.
+	RegisterEvent[*SealedEvent]()
+	RegisterEvent[*ProcessTimeout]()
+}
+
+// RegisterCodec makes a codec available to MixedCodec for its content type.
+// Registering a content type again replaces the earlier codec. JSON and
+// MessagePack are registered by default.
+func RegisterCodec(codec EventCodec) {
+	if codec.ContentType() == "" {
+		panic("RegisterCodec: codec has an empty content type")
+	}
+
+	codecRegistry.Lock()
+	defer codecRegistry.Unlock()
+	codecRegistry.codecs[codec.ContentType()] = codec
+}
+
+// LookupCodec returns the codec registered for a content type.
+func LookupCodec(contentType string) (EventCodec, bool) {
+	codecRegistry.RLock()
+	defer codecRegistry.RUnlock()
+	codec, ok := codecRegistry.codecs[contentType]
+	return codec, ok
+}
+
+// DetectContentType returns the content type tag of an encoded envelope, or an
+// empty string when data is neither a JSON nor a MessagePack envelope. JSON
+// envelopes without a tag, as written before content types existed, are
+// reported as ContentTypeJSON.
+func DetectContentType(data []byte) string {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		var tag struct {
+			ContentType string `json:"content_type"`
+		}
+		if err := json.Unmarshal(trimmed, &tag); err != nil {
+			return ""
+		}
+		if tag.ContentType == "" {
+			return ContentTypeJSON
+		}
+		return tag.ContentType
+	case isMsgpackMap(data):
+		return msgpackContentType(data)
+	}
+	return ""
+}
+
+// MixedCodec writes with one codec and reads envelopes of any registered content
+// type, so a store can change formats without rewriting existing events.
+type MixedCodec struct {
+	write EventCodec
+}
+
+// NewMixedCodec creates a codec that marshals with write and unmarshals by the
+// envelope's content type.
+func NewMixedCodec(write EventCodec) *MixedCodec {
+	return &MixedCodec{write: write}
+}
+
+// ContentType implements EventCodec. It is the content type of the write codec.
+func (c *MixedCodec) ContentType() string { return c.write.ContentType() }
+
+// Marshal implements EventCodec.
+func (c *MixedCodec) Marshal(event DomainEvent) ([]byte, error) {
+	return c.write.Marshal(event)
+}
+
+// Unmarshal implements EventCodec. It returns an error matching ErrUnknownEncoding
+// when the envelope's content type has no registered codec.
+func (c *MixedCodec) Unmarshal(data []byte) (DomainEvent, error) {
+	contentType := DetectContentType(data)
+	if contentType == c.write.ContentType() {
+		return c.write.Unmarshal(data)
+	}
+
+	codec, ok := LookupCodec(contentType)
+	if !ok {
+		return nil, wrapSentinelError(fmt.Sprintf("content type %q is not registered", contentType), ErrUnknownEncoding)
+	}
+	return codec.Unmarshal(data)
+}
+
+// Compressor compresses envelope payloads. Register implementations backed by
+// other packages (for example zstd) with RegisterCompressor so envelopes that
+// name their encoding can be read back.
+type Compressor interface {
+	// Encoding names the compression in Envelope.Encoding.
+	Encoding() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+var compressorRegistry = struct {
+	sync.RWMutex
+	compressors map[string]Compressor
+}{compressors: map[string]Compressor{EncodingGzip: NewGzipCompressor(gzip.DefaultCompression)}}
+
+// RegisterCompressor makes a compressor available for decoding envelopes with its
+// encoding. Registering an encoding again replaces the earlier compressor.
+func RegisterCompressor(compressor Compressor) {
+	if compressor.Encoding() == "" {
+		panic("RegisterCompressor: compressor has an empty encoding")
+	}
+
+	compressorRegistry.Lock()
+	defer compressorRegistry.Unlock()
+	compressorRegistry.compressors[compressor.Encoding()] = compressor
+}
+
+// LookupCompressor returns the compressor registered for an encoding.
+func LookupCompressor(encoding string) (Compressor, bool) {
+	compressorRegistry.RLock()
+	defer compressorRegistry.RUnlock()
+	compressor, ok := compressorRegistry.compressors[encoding]
+	return compressor, ok
+}
+
+type gzipCompressor struct {
+	level int
+}
+
+// NewGzipCompressor returns a gzip Compressor using a compress/gzip level.
+func NewGzipCompressor(level int) Compressor {
+	return gzipCompressor{level: level}
+}
+
+func (c gzipCompressor) Encoding() string { return EncodingGzip }
+
+func (c gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Compress returns the envelope with its payload compressed when the payload is at
+// least threshold bytes and compression makes it smaller. The compressed payload is
+// stored as a base64 JSON string and Encoding names the compressor, so the envelope
+// stays valid JSON.
+func (e Envelope) Compress(compressor Compressor, threshold int) (Envelope, error) {
+	if compressor == nil || e.Encoding != "" || len(e.Payload) < threshold {
+		return e, nil
+	}
+
+	compressed, err := compressor.Compress(e.Payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("compress %s payload: %w", e.Discriminator, err)
+	}
+	payload, err := json.Marshal(compressed)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("compress %s payload: %w", e.Discriminator, err)
+	}
+	if len(payload) >= len(e.Payload) {
+		return e, nil
+	}
+
+	e.Payload = payload
+	e.Encoding = compressor.Encoding()
+	return e, nil
+}
+
+// Decompress returns the envelope with a plain JSON payload. It returns an error
+// matching ErrUnknownEncoding when no compressor is registered for the encoding.
+func (e Envelope) Decompress() (Envelope, error) {
+	if e.Encoding == "" {
+		return e, nil
+	}
+
+	compressor, ok := LookupCompressor(e.Encoding)
+	if !ok {
+		return Envelope{}, wrapSentinelError(fmt.Sprintf("payload encoding %q of %s is not registered", e.Encoding, e.Discriminator), ErrUnknownEncoding)
+	}
+
+	var compressed []byte
+	if err := json.Unmarshal(e.Payload, &compressed); err != nil {
+		return Envelope{}, fmt.Errorf("decompress %s payload: %w", e.Discriminator, err)
+	}
+	payload, err := compressor.Decompress(compressed)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("decompress %s payload: %w", e.Discriminator, err)
+	}
+
+	e.Payload = payload
+	e.Encoding = ""
+	return e, nil
+}
+
+// CodecOption configures an EventCodec.
+type CodecOption func(*codecConfig)
+
+type codecConfig struct {
+	compressor Compressor
+	threshold  int
+}
+
+// WithCompression compresses payloads of at least threshold bytes with compressor.
+func WithCompression(compressor Compressor, threshold int) CodecOption {
+	return func(c *codecConfig) {
+		c.compressor = compressor
+		c.threshold = threshold
+	}
+}
+
+// JSONCodec encodes events as JSON envelopes, optionally compressing large payloads.
+// Decoding needs the event types registered with RegisterEvent and accepts any
+// registered compression, whatever the codec's own settings.
+type JSONCodec struct {
+	config codecConfig
+}
+
+// NewJSONCodec creates a JSON envelope codec.
+func NewJSONCodec(opts ...CodecOption) *JSONCodec {
+	codec := &JSONCodec{}
+	for _, opt := range opts {
+		opt(&codec.config)
+	}
+	return codec
+}
+
+// ContentType implements EventCodec.
+func (c *JSONCodec) ContentType() string { return ContentTypeJSON }
+
+// Marshal implements EventCodec.
+func (c *JSONCodec) Marshal(event DomainEvent) ([]byte, error) {
+	envelope, err := NewEnvelope(event)
+	if err != nil {
+		return nil, err
+	}
+	envelope.ContentType = ContentTypeJSON
+	if envelope, err = envelope.Compress(c.config.compressor, c.config.threshold); err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope)
+}
+
+// Unmarshal implements EventCodec.
+func (c *JSONCodec) Unmarshal(data []byte) (DomainEvent, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("unmarshal envelope: %w", err)
+	}
+	return envelope.Event()
+}
@@ -0,0 +1,114 @@
+package es
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLargeAuditEvent(entity Entity) *DummyAuditLogged {
+	event := &DummyAuditLogged{Reason: strings.Repeat("quarterly report ", 512)}
+	event.SetMetadata(EventMetadata{Entity: entity, EventID: uuid.New(), Sequence: 1})
+	return event
+}
+
+func TestShouldCompressPayloadAboveThreshold(t *testing.T) {
+	// Arrange
+	codec := NewJSONCodec(WithCompression(NewGzipCompressor(gzip.BestCompression), 1024))
+	event := newLargeAuditEvent(NewEntity(uuid.New(), AreaDummy))
+
+	// Act
+	data, err := codec.Marshal(event)
+
+	// Assert
+	require.NoError(t, err)
+	var envelope Envelope
+	require.NoError(t, json.Unmarshal(data, &envelope))
+	assert.Equal(t, EncodingGzip, envelope.Encoding)
+	assert.Less(t, len(data), len(event.Reason))
+
+	decoded, err := codec.Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, event, decoded)
+}
+
+func TestShouldLeaveSmallPayloadUncompressed(t *testing.T) {
+	// Arrange
+	codec := NewJSONCodec(WithCompression(NewGzipCompressor(gzip.DefaultCompression), 1024))
+	entity := NewEntity(uuid.New(), AreaDummy)
+	event := newDummyEvents(entity, 1)[0]
+
+	// Act
+	data, err := codec.Marshal(event)
+
+	// Assert
+	require.NoError(t, err)
+	var envelope Envelope
+	require.NoError(t, json.Unmarshal(data, &envelope))
+	assert.Empty(t, envelope.Encoding)
+	assert.JSONEq(t, `{"Name":"name-1"}`, string(envelope.Payload))
+}
+
+func TestShouldFailToDecodeUnregisteredEncoding(t *testing.T) {
+	// Arrange
+	envelope := Envelope{Discriminator: "dummy_created", Payload: []byte(`"AAAA"`), Encoding: "lz4"}
+
+	// Act
+	_, err := envelope.Event()
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnknownEncoding)
+}
+
+func TestShouldLoadCompressedEventsFromInMemoryStoreTransparently(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	codec := NewJSONCodec(WithCompression(NewGzipCompressor(gzip.DefaultCompression), 256))
+	store := NewInMemoryEventStore(WithStoreCodec(codec))
+	repository := NewRepository(store)
+	dummy := NewDummy()
+	require.NoError(t, dummy.Create("dummy"))
+	require.NoError(t, dummy.LogAudit(strings.Repeat("attached document ", 256)))
+	auditEntity := dummy.GetPendingAudits()[0].Entity
+	require.NoError(t, repository.Save(ctx, dummy))
+
+	// Act
+	loaded := &Dummy{Aggregate: NewAggregate(ctx, AreaDummy, dummy.GetAggregateID())}
+	RegisterHandler(loaded, loaded.OnDummyCreated)
+	err := repository.Load(ctx, loaded)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "dummy", loaded.name)
+	audits, err := store.LoadEvents(ctx, auditEntity, 0)
+	require.NoError(t, err)
+	require.Len(t, audits, 1)
+	assert.Equal(t, strings.Repeat("attached document ", 256), audits[0].(*DummyAuditLogged).Reason)
+}
+
+func TestShouldIsolateEventsStoredAsBytes(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore(WithStoreCodec(NewJSONCodec()))
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 1)
+	require.NoError(t, store.SaveEvents(ctx, entity, events, 0))
+	events[0].(*DummyCreated).Name = "mutated"
+
+	// Act
+	var streamed []DomainEvent
+	for event, err := range store.(StreamingStore).LoadEventStream(ctx, entity, 0) {
+		require.NoError(t, err)
+		streamed = append(streamed, event)
+	}
+
+	// Assert
+	require.Len(t, streamed, 1)
+	assert.Equal(t, "name-1", streamed[0].(*DummyCreated).Name)
+}
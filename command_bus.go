@@ -0,0 +1,203 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	errHandleCommandAlreadyExists = "Handle: handler for command %s already exists"
+	errHandleCommandNilHandler    = "Handle: handler must not be nil"
+	errHandleCommandInterface     = "Handle: command type %s must be concrete"
+)
+
+// CommandHandlerFunc handles a command dispatched through a CommandBus.
+type CommandHandlerFunc func(ctx context.Context, command any) error
+
+// CommandMiddleware wraps command handling to add behavior such as validation,
+// authorization, tracing, or retries without changing the handlers.
+type CommandMiddleware func(next CommandHandlerFunc) CommandHandlerFunc
+
+// CommandBusOption configures a CommandBus.
+type CommandBusOption func(*CommandBus)
+
+// WithCommandMiddleware adds middleware around every handler of the bus. The first
+// middleware is the outermost, as with ChainStore.
+func WithCommandMiddleware(mws ...CommandMiddleware) CommandBusOption {
+	return func(b *CommandBus) {
+		b.middleware = append(b.middleware, mws...)
+	}
+}
+
+// WithCommandIDGenerator sets the generator for the correlation and causation IDs
+// of commands dispatched without them.
+func WithCommandIDGenerator(ids IDGenerator) CommandBusOption {
+	return func(b *CommandBus) {
+		b.ids = ids
+	}
+}
+
+// CommandBus routes commands to the handler registered with Handle for their type.
+// It implements CommandDispatcher, so process managers can dispatch through it.
+type CommandBus struct {
+	mu         sync.RWMutex
+	handlers   map[reflect.Type]CommandHandlerFunc
+	middleware []CommandMiddleware
+	ids        IDGenerator
+}
+
+// NewCommandBus creates an empty command bus.
+func NewCommandBus(opts ...CommandBusOption) *CommandBus {
+	b := &CommandBus{
+		handlers: make(map[reflect.Type]CommandHandlerFunc),
+		ids:      randomIDGenerator{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Handle registers the handler for commands of type C. Commands are matched by
+// their exact dynamic type, so a handler for Order does not receive *Order.
+// It panics when the handler is nil, C is an interface type, or C already has a handler.
+func Handle[C any](bus *CommandBus, handler func(ctx context.Context, command C) error) {
+	if handler == nil {
+		panic(errHandleCommandNilHandler)
+	}
+	commandType := reflect.TypeFor[C]()
+	if commandType.Kind() == reflect.Interface {
+		panic(fmt.Sprintf(errHandleCommandInterface, commandType))
+	}
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	if _, exists := bus.handlers[commandType]; exists {
+		panic(fmt.Sprintf(errHandleCommandAlreadyExists, commandType))
+	}
+	bus.handlers[commandType] = func(ctx context.Context, command any) error {
+		return handler(ctx, command.(C))
+	}
+}
+
+// Dispatch runs the handler registered for the command's type through the bus
+// middleware. A context without a correlation ID starts a new correlation whose
+// ID is also the causation ID; a context with a correlation but no causation gets
+// a new causation ID. Aggregates created by the handler stamp these IDs on their
+// events. Dispatch returns an error matching ErrCommandHandlerNotFound when the
+// command's type has no handler.
+func (b *CommandBus) Dispatch(ctx context.Context, command any) error {
+	commandType := reflect.TypeOf(command)
+	b.mu.RLock()
+	handler, ok := b.handlers[commandType]
+	b.mu.RUnlock()
+	if !ok {
+		return wrapSentinelError(fmt.Sprintf("no handler for command %v", commandType), ErrCommandHandlerNotFound)
+	}
+
+	correlationID, causationID := GetCorrelationID(ctx), GetCausationID(ctx)
+	if correlationID == uuid.Nil {
+		correlationID = b.ids.NewID()
+		causationID = correlationID
+	}
+	if causationID == uuid.Nil {
+		causationID = b.ids.NewID()
+	}
+	ctx = ContextWithTracing(ctx, correlationID, causationID)
+
+	for i := len(b.middleware) - 1; i >= 0; i-- {
+		handler = b.middleware[i](handler)
+	}
+	return handler(ctx, command)
+}
+
+// CommandValidator is implemented by commands that can check their own fields.
+type CommandValidator interface {
+	Validate() error
+}
+
+// ValidateCommands is a CommandMiddleware that calls Validate on commands that
+// implement CommandValidator and rejects invalid ones with an error matching
+// ErrInvalidCommand and the validation error.
+func ValidateCommands(next CommandHandlerFunc) CommandHandlerFunc {
+	return func(ctx context.Context, command any) error {
+		if validator, ok := command.(CommandValidator); ok {
+			if err := validator.Validate(); err != nil {
+				return commandError(ErrInvalidCommand, command, err)
+			}
+		}
+		return next(ctx, command)
+	}
+}
+
+// CommandAuthorizer decides whether the caller in ctx may run a command.
+type CommandAuthorizer interface {
+	AuthorizeCommand(ctx context.Context, command any) error
+}
+
+// CommandAuthorizerFunc adapts a function to the CommandAuthorizer interface.
+type CommandAuthorizerFunc func(ctx context.Context, command any) error
+
+// AuthorizeCommand implements CommandAuthorizer.
+func (f CommandAuthorizerFunc) AuthorizeCommand(ctx context.Context, command any) error {
+	return f(ctx, command)
+}
+
+// AuthorizeCommands returns a CommandMiddleware that asks authorizer before each
+// command runs. Refusals are returned as errors matching ErrUnauthorized and the
+// authorizer's error.
+func AuthorizeCommands(authorizer CommandAuthorizer) CommandMiddleware {
+	return func(next CommandHandlerFunc) CommandHandlerFunc {
+		return func(ctx context.Context, command any) error {
+			if err := authorizer.AuthorizeCommand(ctx, command); err != nil {
+				return commandError(ErrUnauthorized, command, err)
+			}
+			return next(ctx, command)
+		}
+	}
+}
+
+// TraceCommands is a CommandMiddleware that emits an OpenTelemetry span for each
+// command, with its type and the correlation and causation attributes.
+func TraceCommands(next CommandHandlerFunc) CommandHandlerFunc {
+	return func(ctx context.Context, command any) error {
+		attrs := append(tracingAttributes(ctx), attribute.String(attributeCommandType, fmt.Sprintf("%T", command)))
+		ctx, span := otel.Tracer(tracerName).Start(ctx, spanCommandHandle, trace.WithAttributes(attrs...))
+		defer span.End()
+
+		return recordSpanError(span, next(ctx, command))
+	}
+}
+
+// RetryCommands returns a CommandMiddleware that runs a command up to attempts times
+// while it fails with ErrConcurrency. Handlers must load their aggregates on each
+// run for a retry to see the conflicting changes.
+func RetryCommands(attempts int) CommandMiddleware {
+	return func(next CommandHandlerFunc) CommandHandlerFunc {
+		return func(ctx context.Context, command any) error {
+			var err error
+			for range max(attempts, 1) {
+				err = next(ctx, command)
+				if !errors.Is(err, ErrConcurrency) || ctx.Err() != nil {
+					return err
+				}
+			}
+			return err
+		}
+	}
+}
+
+func commandError(sentinel error, command any, err error) error {
+	if errors.Is(err, sentinel) {
+		return err
+	}
+	return fmt.Errorf("%w: %T: %w", sentinel, command, err)
+}
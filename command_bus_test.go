@@ -0,0 +1,212 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type CreateDummy struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (c CreateDummy) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func newDummyCommandBus(repository Repository, opts ...CommandBusOption) *CommandBus {
+	bus := NewCommandBus(opts...)
+	Handle(bus, func(ctx context.Context, command CreateDummy) error {
+		dummy := &Dummy{Aggregate: NewAggregate(ctx, AreaDummy, command.ID)}
+		RegisterHandler(dummy, dummy.OnDummyCreated)
+		if err := repository.Load(ctx, dummy); err != nil {
+			return err
+		}
+		if err := dummy.Create(command.Name); err != nil {
+			return err
+		}
+		return repository.Save(ctx, dummy)
+	})
+	return bus
+}
+
+func TestShouldDispatchCommandToTypedHandler(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	bus := newDummyCommandBus(NewRepository(store))
+	command := CreateDummy{ID: uuid.New(), Name: "created"}
+
+	// Act
+	err := bus.Dispatch(ctx, command)
+
+	// Assert
+	require.NoError(t, err)
+	events, err := store.LoadEvents(ctx, NewEntity(command.ID, AreaDummy), 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.NotEqual(t, uuid.Nil, events[0].GetCorrelationID())
+	assert.Equal(t, events[0].GetCorrelationID(), events[0].GetCausationID())
+}
+
+func TestShouldKeepTracingContextOfDispatchedCommand(t *testing.T) {
+	// Arrange
+	ids := NewSequentialIDGenerator()
+	bus := NewCommandBus(WithCommandIDGenerator(ids))
+	var correlationIDs, causationIDs []uuid.UUID
+	Handle(bus, func(ctx context.Context, _ CreateDummy) error {
+		correlationIDs = append(correlationIDs, GetCorrelationID(ctx))
+		causationIDs = append(causationIDs, GetCausationID(ctx))
+		return nil
+	})
+	correlationID, causationID := uuid.New(), uuid.New()
+
+	// Act
+	require.NoError(t, bus.Dispatch(ContextWithTracing(context.Background(), correlationID, causationID), CreateDummy{}))
+	require.NoError(t, bus.Dispatch(ContextWithTracing(context.Background(), correlationID, uuid.Nil), CreateDummy{}))
+	require.NoError(t, bus.Dispatch(context.Background(), CreateDummy{}))
+
+	// Assert
+	assert.Equal(t, []uuid.UUID{correlationID, correlationID, SequentialID(2)}, correlationIDs)
+	assert.Equal(t, []uuid.UUID{causationID, SequentialID(1), SequentialID(2)}, causationIDs)
+}
+
+func TestShouldReturnErrorWhenCommandHasNoHandler(t *testing.T) {
+	// Arrange
+	bus := NewCommandBus()
+	Handle(bus, func(context.Context, CreateDummy) error { return nil })
+
+	// Act
+	err := bus.Dispatch(context.Background(), &CreateDummy{})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrCommandHandlerNotFound)
+}
+
+func TestShouldPanicWhenCommandHandlerRegisteredTwice(t *testing.T) {
+	// Arrange
+	bus := NewCommandBus()
+	Handle(bus, func(context.Context, CreateDummy) error { return nil })
+
+	// Act & Assert
+	assert.Panics(t, func() {
+		Handle(bus, func(context.Context, CreateDummy) error { return nil })
+	})
+	assert.Panics(t, func() {
+		Handle(bus, func(context.Context, any) error { return nil })
+	})
+}
+
+func TestShouldRejectInvalidAndUnauthorizedCommands(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	denied := errors.New("caller may not create dummies")
+	authorizer := CommandAuthorizerFunc(func(_ context.Context, command any) error {
+		if command.(CreateDummy).Name == "forbidden" {
+			return denied
+		}
+		return nil
+	})
+	store := NewInMemoryEventStore()
+	bus := newDummyCommandBus(NewRepository(store),
+		WithCommandMiddleware(ValidateCommands, AuthorizeCommands(authorizer)))
+
+	// Act
+	invalidErr := bus.Dispatch(ctx, CreateDummy{ID: uuid.New()})
+	unauthorizedErr := bus.Dispatch(ctx, CreateDummy{ID: uuid.New(), Name: "forbidden"})
+
+	// Assert
+	assert.ErrorIs(t, invalidErr, ErrInvalidCommand)
+	assert.ErrorContains(t, invalidErr, "name is required")
+	assert.ErrorIs(t, unauthorizedErr, ErrUnauthorized)
+	assert.ErrorIs(t, unauthorizedErr, denied)
+	page, err := store.(*InMemoryEventStore).ListStreams(ctx, StreamFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Entities)
+}
+
+func TestShouldRetryCommandsOnConcurrencyErrors(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	calls := 0
+	bus := NewCommandBus(WithCommandMiddleware(RetryCommands(3)))
+	Handle(bus, func(context.Context, CreateDummy) error {
+		calls++
+		if calls < 3 {
+			return ErrConcurrency
+		}
+		return nil
+	})
+	failing := NewCommandBus(WithCommandMiddleware(RetryCommands(2)))
+	failingCalls := 0
+	Handle(failing, func(context.Context, CreateDummy) error {
+		failingCalls++
+		return ErrConcurrency
+	})
+
+	// Act
+	err := bus.Dispatch(ctx, CreateDummy{})
+	failingErr := failing.Dispatch(ctx, CreateDummy{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.ErrorIs(t, failingErr, ErrConcurrency)
+	assert.Equal(t, 2, failingCalls)
+}
+
+func TestShouldEmitSpanForDispatchedCommand(t *testing.T) {
+	// Arrange
+	spanRecorder := setupSpanRecorder(t)
+	correlationID, causationID := uuid.New(), uuid.New()
+	ctx := ContextWithTracing(context.Background(), correlationID, causationID)
+	handlerErr := errors.New("handler failed")
+	bus := NewCommandBus(WithCommandMiddleware(TraceCommands))
+	Handle(bus, func(context.Context, CreateDummy) error { return handlerErr })
+
+	// Act
+	err := bus.Dispatch(ctx, CreateDummy{})
+
+	// Assert
+	assert.ErrorIs(t, err, handlerErr)
+	spans := spanRecorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, spanCommandHandle, spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assertSpanStringAttribute(t, spans[0], attributeCommandType, "es.CreateDummy")
+	assertSpanStringAttribute(t, spans[0], attributeCorrelationID, correlationID.String())
+	assertSpanStringAttribute(t, spans[0], attributeCausationID, causationID.String())
+}
+
+func TestShouldDispatchProcessCommandsThroughCommandBus(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	bus := NewCommandBus()
+	var requested []RequestPayment
+	Handle(bus, func(_ context.Context, command RequestPayment) error {
+		requested = append(requested, command)
+		return nil
+	})
+	pm := NewProcessManager(AreaFulfillment, NewRepository(NewInMemoryEventStore()), bus, NewFulfillment)
+	HandleProcessEvent(pm, func(_ context.Context, f *Fulfillment, e *OrderPlaced, step *ProcessStep) error {
+		step.Dispatch(RequestPayment{OrderID: e.OrderID})
+		return f.Record("payment_requested")
+	}, StartsProcess())
+	orderID := uuid.New()
+
+	// Act
+	err := pm.Handle(ctx, newTriggerEvent(&OrderPlaced{OrderID: orderID}, uuid.New()))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []RequestPayment{{OrderID: orderID}}, requested)
+}
@@ -4,10 +4,25 @@ import (
 	"context"
 )
 
-// WithEventMetadata creates a new context with tracing information from a domain event.
-// This enables correlation and causation tracking across service boundaries.
+// WithEventMetadata creates a new context for reacting to a domain event. It keeps
+// the event's correlation ID and uses the event's ID as the causation ID, so events
+// raised in reaction record which event caused them, across service boundaries.
 func WithEventMetadata(ctx context.Context, event DomainEvent) context.Context {
 	metadata := event.GetMetadata()
-	ctx = ContextWithTracing(ctx, metadata.CorrelationID, metadata.CausationID)
-	return ctx
+	return ContextWithTracing(ctx, metadata.CorrelationID, metadata.EventID)
+}
+
+type auditWriteContextKey struct{}
+
+// withAuditWrite marks the context of a SaveEvents call that persists an audit batch stream.
+func withAuditWrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditWriteContextKey{}, true)
+}
+
+// IsAuditWrite reports whether a SaveEvents call made by Repository.Save is persisting
+// an audit batch stream rather than a domain stream. Store implementations and
+// middleware can use it to route or instrument audit writes separately.
+func IsAuditWrite(ctx context.Context) bool {
+	audit, _ := ctx.Value(auditWriteContextKey{}).(bool)
+	return audit
 }
@@ -12,16 +12,16 @@ func TestShouldCreateContextWithEventMetadata(t *testing.T) {
 	// Arrange
 	originalCtx := context.Background()
 	entity := NewEntity(uuid.New(), "test-area")
+	eventID := uuid.New()
 	correlationID := uuid.New()
-	causationID := uuid.New()
 
 	event := &mockDomainEvent{
 		DomainEventBase: &DomainEventBase{
 			Metadata: EventMetadata{
 				Entity:        entity,
-				EventID:       uuid.New(),
+				EventID:       eventID,
 				CorrelationID: correlationID,
-				CausationID:   causationID,
+				CausationID:   uuid.New(),
 				Timestamp:     123456789,
 				Sequence:      1,
 			},
@@ -35,7 +35,7 @@ func TestShouldCreateContextWithEventMetadata(t *testing.T) {
 	assert.NotNil(t, newCtx)
 	assert.NotEqual(t, originalCtx, newCtx)
 	assert.Equal(t, correlationID, GetCorrelationID(newCtx))
-	assert.Equal(t, causationID, GetCausationID(newCtx))
+	assert.Equal(t, eventID, GetCausationID(newCtx))
 }
 
 func TestShouldPreserveExistingContextValues(t *testing.T) {
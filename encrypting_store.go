@@ -0,0 +1,319 @@
+package es
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"iter"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const sealedEventDiscriminator = "es://sealed_event"
+
+// KeyProvider resolves AES keys for EncryptingStore. Global-scoped entities
+// use uuid.Nil as the tenant ID.
+type KeyProvider interface {
+	// EncryptionKey returns the current key for a tenant and the ID it is stored under.
+	EncryptionKey(ctx context.Context, tenantID uuid.UUID) (keyID string, key []byte, err error)
+
+	// DecryptionKey returns a tenant key by ID, including keys that have since been
+	// rotated out. It returns an error matching ErrKeyNotFound when the key is gone.
+	DecryptionKey(ctx context.Context, tenantID uuid.UUID, keyID string) ([]byte, error)
+}
+
+// SealedEvent is the form in which EncryptingStore hands events to the wrapped store.
+// Metadata and the original discriminator stay in plaintext for routing; the payload
+// is encrypted with AES-GCM under the tenant key named by KeyID.
+type SealedEvent struct {
+	DomainEventBase
+	EventType  string `json:"event_type"`
+	KeyID      string `json:"key_id"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// GetDiscriminator implements polymorphic.Polymorphic.
+func (e *SealedEvent) GetDiscriminator() string { return sealedEventDiscriminator }
+
+// GetAreas returns the area of the stream the event was sealed for.
+func (e *SealedEvent) GetAreas() []string { return []string{e.Metadata.Entity.Area} }
+
+// GetSpaces returns the same values as GetAreas.
+func (e *SealedEvent) GetSpaces() []string { return e.GetAreas() }
+
+// EncryptingStoreOption configures an EncryptingStore.
+type EncryptingStoreOption func(*EncryptingStore)
+
+// WithGlobalEncryption also encrypts global-scoped streams, using the key that the
+// KeyProvider returns for uuid.Nil. By default only tenant-scoped streams are encrypted.
+func WithGlobalEncryption() EncryptingStoreOption {
+	return func(s *EncryptingStore) {
+		s.encryptGlobal = true
+	}
+}
+
+// EncryptingStore encrypts event payloads before they reach the wrapped store and
+// decrypts them on every read path, so the wrapped store never sees plaintext
+// payloads of tenant-scoped streams. Event types must be registered with
+// RegisterEvent so they can be rebuilt after decryption.
+type EncryptingStore struct {
+	ForwardingStore
+	keys          KeyProvider
+	encryptGlobal bool
+}
+
+// NewEncryptingStore wraps store with payload encryption using keys from keys.
+func NewEncryptingStore(store Store, keys KeyProvider, opts ...EncryptingStoreOption) *EncryptingStore {
+	s := &EncryptingStore{ForwardingStore: ForwardingStore{Next: store}, keys: keys}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Encrypting returns a StoreMiddleware that wraps a store in an EncryptingStore.
+func Encrypting(keys KeyProvider, opts ...EncryptingStoreOption) StoreMiddleware {
+	return func(next Store) Store {
+		return NewEncryptingStore(next, keys, opts...)
+	}
+}
+
+// SaveEvents implements Store.SaveEvents.
+func (s *EncryptingStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
+	sealed, err := s.sealAll(ctx, entity, events)
+	if err != nil {
+		return err
+	}
+	return s.Next.SaveEvents(ctx, entity, sealed, expectedSequence)
+}
+
+// CopyEvents implements StreamCopier.CopyEvents.
+func (s *EncryptingStore) CopyEvents(ctx context.Context, entity Entity, events []DomainEvent, startSequence uint64) error {
+	sealed, err := s.sealAll(ctx, entity, events)
+	if err != nil {
+		return err
+	}
+	return CopyEvents(ctx, s.Next, entity, sealed, startSequence)
+}
+
+// sealAll seals events under the entity's current key, or returns them unchanged
+// when the entity's stream is not encrypted.
+func (s *EncryptingStore) sealAll(ctx context.Context, entity Entity, events []DomainEvent) ([]DomainEvent, error) {
+	if !s.encrypts(entity) {
+		return events, nil
+	}
+
+	keyID, key, err := s.keys.EncryptionKey(ctx, entity.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]DomainEvent, 0, len(events))
+	for _, event := range events {
+		sealedEvent, err := seal(aead, keyID, event)
+		if err != nil {
+			return nil, err
+		}
+		sealed = append(sealed, sealedEvent)
+	}
+	return sealed, nil
+}
+
+// LoadEvents implements Store.LoadEvents.
+func (s *EncryptingStore) LoadEvents(ctx context.Context, entity Entity, minSequence uint64) ([]DomainEvent, error) {
+	events, err := s.Next.LoadEvents(ctx, entity, minSequence)
+	if err != nil {
+		return nil, err
+	}
+	return s.openAll(ctx, events)
+}
+
+// LoadEventStream implements StreamingStore.LoadEventStream.
+func (s *EncryptingStore) LoadEventStream(ctx context.Context, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error] {
+	return func(yield func(DomainEvent, error) bool) {
+		opener := s.newOpener()
+		for event, err := range StreamEvents(ctx, s.Next, entity, minSequence) {
+			if err == nil {
+				event, err = opener.open(ctx, event)
+			}
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ReadStream implements StreamReader.ReadStream.
+func (s *EncryptingStore) ReadStream(ctx context.Context, entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	events, err := ReadStream(ctx, s.Next, entity, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.openAll(ctx, events)
+}
+
+func (s *EncryptingStore) encrypts(entity Entity) bool {
+	return entity.Scope == ScopeTenant || s.encryptGlobal
+}
+
+func (s *EncryptingStore) openAll(ctx context.Context, events []DomainEvent) ([]DomainEvent, error) {
+	opener := s.newOpener()
+	opened := make([]DomainEvent, 0, len(events))
+	for _, event := range events {
+		event, err := opener.open(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		opened = append(opened, event)
+	}
+	return opened, nil
+}
+
+// opener decrypts sealed events, caching one cipher per tenant key for a single read.
+type opener struct {
+	keys    KeyProvider
+	ciphers map[tenantKey]cipher.AEAD
+}
+
+type tenantKey struct {
+	tenantID uuid.UUID
+	keyID    string
+}
+
+func (s *EncryptingStore) newOpener() *opener {
+	return &opener{keys: s.keys, ciphers: make(map[tenantKey]cipher.AEAD)}
+}
+
+func (o *opener) open(ctx context.Context, event DomainEvent) (DomainEvent, error) {
+	sealed, ok := event.(*SealedEvent)
+	if !ok {
+		return event, nil
+	}
+
+	id := tenantKey{tenantID: sealed.Metadata.Entity.TenantID, keyID: sealed.KeyID}
+	aead, ok := o.ciphers[id]
+	if !ok {
+		key, err := o.keys.DecryptionKey(ctx, id.tenantID, id.keyID)
+		if err != nil {
+			return nil, err
+		}
+		if aead, err = newAEAD(key); err != nil {
+			return nil, err
+		}
+		o.ciphers[id] = aead
+	}
+
+	payload, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, sealedAdditionalData(sealed.EventType, sealed.Metadata))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s event %s: %w", sealed.EventType, sealed.Metadata.EventID, err)
+	}
+	return Envelope{Discriminator: sealed.EventType, Metadata: sealed.Metadata, Payload: payload}.Event()
+}
+
+func seal(aead cipher.AEAD, keyID string, event DomainEvent) (*SealedEvent, error) {
+	envelope, err := NewEnvelope(event)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := &SealedEvent{
+		EventType: envelope.Discriminator,
+		KeyID:     keyID,
+		Nonce:     nonce,
+	}
+	sealed.Ciphertext = aead.Seal(nil, nonce, envelope.Payload, sealedAdditionalData(envelope.Discriminator, envelope.Metadata))
+	sealed.SetMetadata(envelope.Metadata)
+	return sealed, nil
+}
+
+// sealedAdditionalData binds a ciphertext to its stream, event ID, and type, so a
+// payload cannot be replayed under another event or into another stream, even one
+// encrypted with the same key.
+func sealedAdditionalData(discriminator string, metadata EventMetadata) []byte {
+	entity := metadata.Entity
+	data := make([]byte, 0, 3*len(uuid.UUID{})+len(entity.Area)+1+len(discriminator))
+	data = append(data, metadata.EventID[:]...)
+	data = append(data, entity.TenantID[:]...)
+	data = append(data, entity.ID[:]...)
+	data = append(data, entity.Area...)
+	data = append(data, 0)
+	return append(data, discriminator...)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// InMemoryKeyProvider is a KeyProvider that keeps tenant keys in memory.
+// It is intended for tests and development. It is safe for concurrent use.
+type InMemoryKeyProvider struct {
+	mu      sync.RWMutex
+	current map[uuid.UUID]string
+	keys    map[tenantKey][]byte
+}
+
+// NewInMemoryKeyProvider creates an empty in-memory key provider.
+func NewInMemoryKeyProvider() *InMemoryKeyProvider {
+	return &InMemoryKeyProvider{
+		current: make(map[uuid.UUID]string),
+		keys:    make(map[tenantKey][]byte),
+	}
+}
+
+// SetKey stores key under keyID for a tenant and makes it the tenant's current key.
+// Earlier keys remain available for decryption. Keys must be 16, 24, or 32 bytes.
+func (p *InMemoryKeyProvider) SetKey(tenantID uuid.UUID, keyID string, key []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keys[tenantKey{tenantID: tenantID, keyID: keyID}] = append([]byte(nil), key...)
+	p.current[tenantID] = keyID
+}
+
+// EncryptionKey implements KeyProvider.
+func (p *InMemoryKeyProvider) EncryptionKey(ctx context.Context, tenantID uuid.UUID) (string, []byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	keyID, ok := p.current[tenantID]
+	if !ok {
+		return "", nil, keyNotFoundError(tenantID, "")
+	}
+	return keyID, p.keys[tenantKey{tenantID: tenantID, keyID: keyID}], nil
+}
+
+// DecryptionKey implements KeyProvider.
+func (p *InMemoryKeyProvider) DecryptionKey(ctx context.Context, tenantID uuid.UUID, keyID string) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key, ok := p.keys[tenantKey{tenantID: tenantID, keyID: keyID}]
+	if !ok {
+		return nil, keyNotFoundError(tenantID, keyID)
+	}
+	return key, nil
+}
+
+func keyNotFoundError(tenantID uuid.UUID, keyID string) error {
+	if keyID == "" {
+		return wrapSentinelError(fmt.Sprintf("no encryption key for tenant %s", tenantID), ErrKeyNotFound)
+	}
+	return wrapSentinelError(fmt.Sprintf("encryption key %s for tenant %s not found", keyID, tenantID), ErrKeyNotFound)
+}
@@ -0,0 +1,239 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestShouldEncryptTenantPayloadsAtRest(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	keys := NewInMemoryKeyProvider()
+	tenantID := uuid.New()
+	keys.SetKey(tenantID, "k1", newTestKey(1))
+	store := NewEncryptingStore(inner, keys)
+	entity := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 2)
+
+	// Act
+	err := store.SaveEvents(ctx, entity, events, 0)
+
+	// Assert
+	require.NoError(t, err)
+	raw, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	require.Len(t, raw, 2)
+	sealed, ok := raw[0].(*SealedEvent)
+	require.True(t, ok)
+	assert.Equal(t, "dummy_created", sealed.EventType)
+	assert.Equal(t, "k1", sealed.KeyID)
+	assert.Equal(t, events[0].GetMetadata(), sealed.GetMetadata())
+	assert.NotContains(t, string(sealed.Ciphertext), "name-1")
+
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events, loaded)
+}
+
+func TestShouldDecryptOnEveryReadPath(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	keys := NewInMemoryKeyProvider()
+	tenantID := uuid.New()
+	keys.SetKey(tenantID, "k1", newTestKey(1))
+	store := NewEncryptingStore(NewInMemoryEventStore(), keys)
+	entity := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 3)
+	require.NoError(t, store.SaveEvents(ctx, entity, events, 0))
+
+	// Act
+	var streamed []DomainEvent
+	for event, err := range store.LoadEventStream(ctx, entity, 0) {
+		require.NoError(t, err)
+		streamed = append(streamed, event)
+	}
+	read, err := store.ReadStream(ctx, entity, ReadOptions{From: 2})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, events, streamed)
+	assert.Equal(t, events[1:], read)
+}
+
+func TestShouldDecryptEventsSealedWithRotatedKey(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	keys := NewInMemoryKeyProvider()
+	tenantID := uuid.New()
+	keys.SetKey(tenantID, "k1", newTestKey(1))
+	store := NewEncryptingStore(NewInMemoryEventStore(), keys)
+	entity := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 2)
+	require.NoError(t, store.SaveEvents(ctx, entity, events[:1], 0))
+	keys.SetKey(tenantID, "k2", newTestKey(2))
+
+	// Act
+	err := store.SaveEvents(ctx, entity, events[1:], 1)
+
+	// Assert
+	require.NoError(t, err)
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events, loaded)
+}
+
+func TestShouldFailToReadWhenTenantKeyIsMissing(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	keys := NewInMemoryKeyProvider()
+	tenantID := uuid.New()
+	keys.SetKey(tenantID, "k1", newTestKey(1))
+	entity := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	require.NoError(t, NewEncryptingStore(inner, keys).SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0))
+	store := NewEncryptingStore(inner, NewInMemoryKeyProvider())
+
+	// Act
+	_, err := store.LoadEvents(ctx, entity, 0)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestShouldLeaveGlobalStreamsInPlaintextByDefault(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	store := NewEncryptingStore(inner, NewInMemoryKeyProvider())
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 1)
+
+	// Act
+	err := store.SaveEvents(ctx, entity, events, 0)
+
+	// Assert
+	require.NoError(t, err)
+	raw, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events, raw)
+}
+
+func TestShouldEncryptGlobalStreamsWhenEnabled(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	keys := NewInMemoryKeyProvider()
+	keys.SetKey(uuid.Nil, "global", newTestKey(3))
+	store := NewEncryptingStore(inner, keys, WithGlobalEncryption())
+	entity := NewEntity(uuid.New(), AreaDummy)
+
+	// Act
+	err := store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0)
+
+	// Assert
+	require.NoError(t, err)
+	raw, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.IsType(t, &SealedEvent{}, raw[0])
+}
+
+func TestShouldRejectCiphertextMovedToAnotherEvent(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	keys := NewInMemoryKeyProvider()
+	tenantID := uuid.New()
+	keys.SetKey(tenantID, "k1", newTestKey(1))
+	store := NewEncryptingStore(inner, keys)
+	entity := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 2), 0))
+	raw, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	raw[1].(*SealedEvent).Ciphertext = raw[0].(*SealedEvent).Ciphertext
+	raw[1].(*SealedEvent).Nonce = raw[0].(*SealedEvent).Nonce
+
+	// Act
+	_, err = store.LoadEvents(ctx, entity, 0)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestShouldRejectCiphertextMovedToAnotherStream(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	keys := NewInMemoryKeyProvider()
+	tenantID := uuid.New()
+	keys.SetKey(tenantID, "k1", newTestKey(1))
+	store := NewEncryptingStore(inner, keys)
+	entity := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	other := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0))
+	raw, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	moved := *raw[0].(*SealedEvent)
+	moved.Metadata.Entity = other
+	require.NoError(t, inner.SaveEvents(ctx, other, []DomainEvent{&moved}, 0))
+
+	// Act
+	_, err = store.LoadEvents(ctx, other, 0)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestShouldRoundTripSealedEventsThroughByteStore(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	keys := NewInMemoryKeyProvider()
+	tenantID := uuid.New()
+	keys.SetKey(tenantID, "k1", newTestKey(1))
+	store := NewEncryptingStore(NewInMemoryEventStore(WithStoreCodec(NewJSONCodec())), keys)
+	entity := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 2)
+
+	// Act
+	err := store.SaveEvents(ctx, entity, events, 0)
+
+	// Assert
+	require.NoError(t, err)
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events, loaded)
+}
+
+func TestShouldSealEventsCopiedThroughEncryptingStore(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	keys := NewInMemoryKeyProvider()
+	tenantID := uuid.New()
+	keys.SetKey(tenantID, "k1", newTestKey(1))
+	store := NewEncryptingStore(inner, keys)
+	entity := NewTenantEntity(tenantID, uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 4)
+
+	// Act
+	err := CopyEvents(ctx, store, entity, events[2:], 2)
+
+	// Assert
+	require.NoError(t, err)
+	raw, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	require.Len(t, raw, 2)
+	assert.IsType(t, &SealedEvent{}, raw[0])
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events[2:], loaded)
+}
@@ -48,8 +48,20 @@ func NewTenantEntityInArea(tenantID, id uuid.UUID, area string) Entity {
 // It shares Area, TenantID, and Scope with the domain entity, but uses a fresh ID so
 // each audit batch is an independent stream.
 func AuditStreamEntity(domain Entity) Entity {
+	return auditStreamEntity(domain, uuid.New())
+}
+
+// AuditStreamEntityFor returns the audit batch stream identity that Aggregate.Audit
+// derives for a batch whose first event has firstEventID. The ID is a name-based
+// UUID of the domain entity's ID and the event ID, so tools can tell which domain
+// stream an audit batch stream belongs to from the stream's first event.
+func AuditStreamEntityFor(domain Entity, firstEventID uuid.UUID) Entity {
+	return auditStreamEntity(domain, uuid.NewSHA1(domain.ID, firstEventID[:]))
+}
+
+func auditStreamEntity(domain Entity, id uuid.UUID) Entity {
 	return Entity{
-		ID:       uuid.New(),
+		ID:       id,
 		Area:     domain.Area,
 		TenantID: domain.TenantID,
 		Scope:    domain.Scope,
@@ -80,6 +80,23 @@ func TestShouldCreateAuditStreamEntityWithGeneratedIDAndSameArea(t *testing.T) {
 	assert.Equal(t, domain.Scope, audit.Scope)
 }
 
+func TestShouldDeriveAuditStreamEntityFromDomainAndFirstEvent(t *testing.T) {
+	// Arrange
+	domain := NewTenantEntity(uuid.New(), uuid.New(), "users")
+	eventID := uuid.New()
+
+	// Act
+	audit := AuditStreamEntityFor(domain, eventID)
+
+	// Assert
+	assert.Equal(t, audit, AuditStreamEntityFor(domain, eventID))
+	assert.NotEqual(t, audit, AuditStreamEntityFor(domain, uuid.New()))
+	assert.NotEqual(t, audit, AuditStreamEntityFor(NewTenantEntity(domain.TenantID, uuid.New(), "users"), eventID))
+	assert.Equal(t, domain.Area, audit.Area)
+	assert.Equal(t, domain.TenantID, audit.TenantID)
+	assert.Equal(t, domain.Scope, audit.Scope)
+}
+
 func TestShouldReturnCorrectAreaForGlobalEntity(t *testing.T) {
 	// Arrange
 	entity := NewEntity(uuid.New(), "test-area")
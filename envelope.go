@@ -0,0 +1,88 @@
+package es
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// metadataField is the JSON field DomainEventBase uses for EventMetadata.
+const metadataField = "metadata"
+
+// Envelope is the serialized form of a DomainEvent. Routing metadata is kept apart
+// from the payload so stores and decorators can index, route, or transform one
+// without touching the other.
+type Envelope struct {
+	Discriminator string          `json:"discriminator"`
+	Metadata      EventMetadata   `json:"metadata"`
+	Payload       json.RawMessage `json:"payload"`
+	// Encoding names the compression applied to Payload, or is empty for plain JSON.
+	Encoding string `json:"encoding,omitempty"`
+	// ContentType names the EventCodec that wrote the envelope; see DetectContentType.
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// NewEnvelope serializes an event. The payload is the event's JSON encoding
+// without its metadata field.
+func NewEnvelope(event DomainEvent) (Envelope, error) {
+	payload, err := marshalPayload(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{
+		Discriminator: event.GetDiscriminator(),
+		Metadata:      event.GetMetadata(),
+		Payload:       payload,
+	}, nil
+}
+
+// Event rebuilds the envelope's event from the registry, decompressing and
+// upcasting the payload when needed, and restores its metadata. It returns an
+// error matching ErrUnknownEvent when the discriminator is not registered.
+func (e Envelope) Event() (DomainEvent, error) {
+	e, err := e.Decompress()
+	if err != nil {
+		return nil, err
+	}
+	if e, err = e.Upcast(); err != nil {
+		return nil, err
+	}
+	event, err := NewEvent(e.Discriminator)
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalPayload(e.Payload, event); err != nil {
+		return nil, err
+	}
+	event.SetMetadata(e.Metadata)
+	return event, nil
+}
+
+func marshalPayload(event DomainEvent) (json.RawMessage, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s payload: %w", event.GetDiscriminator(), err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("marshal %s payload: %w", event.GetDiscriminator(), err)
+	}
+	delete(fields, metadataField)
+
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s payload: %w", event.GetDiscriminator(), err)
+	}
+	return payload, nil
+}
+
+func unmarshalPayload(payload json.RawMessage, event DomainEvent) error {
+	if len(payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return fmt.Errorf("unmarshal %s payload: %w", event.GetDiscriminator(), err)
+	}
+	return nil
+}
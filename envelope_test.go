@@ -0,0 +1,37 @@
+package es
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldRoundTripEventThroughEnvelope(t *testing.T) {
+	// Arrange
+	entity := NewEntity(uuid.New(), AreaDummy)
+	event := newDummyEvents(entity, 1)[0]
+
+	// Act
+	envelope, err := NewEnvelope(event)
+	require.NoError(t, err)
+	decoded, err := envelope.Event()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "dummy_created", envelope.Discriminator)
+	assert.JSONEq(t, `{"Name":"name-1"}`, string(envelope.Payload))
+	assert.Equal(t, event, decoded)
+}
+
+func TestShouldFailToDecodeUnregisteredEnvelope(t *testing.T) {
+	// Arrange
+	envelope := Envelope{Discriminator: "not_registered", Payload: []byte(`{}`)}
+
+	// Act
+	_, err := envelope.Event()
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
@@ -16,8 +16,33 @@ var (
 	// ErrEventHandlerNotFound is available to alternate aggregate workflows that need
 	// explicit handler lookup failures.
 	ErrEventHandlerNotFound = errors.New("event handler not found")
+	// ErrCommandHandlerNotFound is returned by CommandBus.Dispatch when no handler is registered for a command's type.
+	ErrCommandHandlerNotFound = errors.New("command handler not found")
+	// ErrInvalidCommand is returned by ValidateCommands when a command fails validation.
+	ErrInvalidCommand = errors.New("invalid command")
+	// ErrUnauthorized is returned by AuthorizeCommands when a command is refused.
+	ErrUnauthorized = errors.New("unauthorized")
 	// ErrInvalidEntity is returned when entity validation fails.
 	ErrInvalidEntity = errors.New("invalid entity")
+	// ErrStreamDeleted is returned when reading from or appending to a stream that has been soft deleted.
+	ErrStreamDeleted = errors.New("stream deleted")
+	// ErrInvalidSequence is returned when imported events of a stream do not have consecutive sequences.
+	ErrInvalidSequence = errors.New("invalid sequence")
+	// ErrChecksumMismatch is returned by Migrator when a copied stream does not match its source.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrUnknownEvent is returned when decoding an event whose discriminator was not registered with RegisterEvent.
+	ErrUnknownEvent = errors.New("unknown event")
+	// ErrUnknownEncoding is returned when decoding a payload whose compression has no registered Compressor.
+	ErrUnknownEncoding = errors.New("unknown encoding")
+	// ErrKeyNotFound is returned by a KeyProvider when no key exists for a tenant or key ID.
+	ErrKeyNotFound = errors.New("encryption key not found")
+	// ErrSubjectForgotten is returned when writing personal data for a subject whose key was destroyed.
+	ErrSubjectForgotten = errors.New("subject forgotten")
+	// ErrInjectedFault is returned by FaultyStore when a scripted fault has no explicit error.
+	ErrInjectedFault = errors.New("injected fault")
+	// ErrUnsupported is returned when an operation needs an optional store capability
+	// that the configured store does not implement.
+	ErrUnsupported = errors.New("operation not supported by store")
 )
 
 type wrappedSentinelError struct {
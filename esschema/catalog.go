@@ -0,0 +1,289 @@
+package esschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fgrzl/es"
+)
+
+// schemaSuffix ends the file name of every catalog document.
+const schemaSuffix = ".schema.json"
+
+var (
+	// ErrIncompatible is matched by errors that report breaking schema changes.
+	ErrIncompatible = errors.New("incompatible schema change")
+	// ErrOutdated is returned by Check when the catalog on disk differs from the
+	// registered events in compatible ways.
+	ErrOutdated = errors.New("schema catalog is out of date")
+)
+
+// IncompatibleError lists the breaking changes found between a catalog on disk
+// and the registered events. It matches ErrIncompatible.
+type IncompatibleError struct {
+	Changes []Change
+}
+
+func (e *IncompatibleError) Error() string {
+	lines := make([]string, 0, len(e.Changes)+1)
+	lines = append(lines, fmt.Sprintf("%d incompatible schema change(s):", len(e.Changes)))
+	for _, change := range e.Changes {
+		lines = append(lines, "  "+change.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *IncompatibleError) Unwrap() error { return ErrIncompatible }
+
+// Document is one catalog entry: an event's schema filed under one of its areas.
+type Document struct {
+	Area          string
+	Discriminator string
+	Schema        *Schema
+}
+
+// Path returns the document's slash-separated path inside a catalog directory,
+// <area>/<discriminator>.schema.json, with unsafe characters replaced.
+func (d Document) Path() string {
+	return fileName(d.Area) + "/" + fileName(d.Discriminator) + schemaSuffix
+}
+
+// Catalog generates a document per event and area, ordered by path. Events
+// without areas, such as those that take their area from their metadata, are
+// filed under "_".
+func Catalog(registrations []es.EventRegistration) []Document {
+	var documents []Document
+	for _, registration := range registrations {
+		schema := Generate(registration)
+		areas := slices.DeleteFunc(slices.Clone(registration.Areas), func(area string) bool { return area == "" })
+		if len(areas) == 0 {
+			areas = []string{"_"}
+		}
+		for _, area := range areas {
+			documents = append(documents, Document{Area: area, Discriminator: registration.Discriminator, Schema: schema})
+		}
+	}
+	slices.SortFunc(documents, func(a, b Document) int {
+		return strings.Compare(a.Path(), b.Path())
+	})
+	return documents
+}
+
+// Option configures Write.
+type Option func(*options)
+
+type options struct {
+	force bool
+}
+
+// Force makes Write replace the catalog even when the changes are incompatible,
+// removing documents of events that are no longer registered.
+func Force() Option {
+	return func(o *options) {
+		o.force = true
+	}
+}
+
+// Write writes the catalog of every registered event under dir. It writes nothing
+// and returns an *IncompatibleError when a document already in dir would change
+// incompatibly or would be removed, unless Force is given.
+func Write(dir string, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	documents := Catalog(es.RegisteredEvents())
+	existing, err := readCatalog(dir)
+	if err != nil {
+		return err
+	}
+	if changes := compareCatalog(existing, documents); len(changes) > 0 && !o.force {
+		return &IncompatibleError{Changes: changes}
+	}
+
+	for _, document := range documents {
+		data, err := encode(document.Schema)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, filepath.FromSlash(document.Path()))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+		delete(existing, document.Path())
+	}
+	for path := range existing {
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(path))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check compares the catalog in dir with the registered events without writing.
+// It returns an *IncompatibleError for breaking changes and an error matching
+// ErrOutdated when documents are missing or differ compatibly.
+func Check(dir string) error {
+	documents := Catalog(es.RegisteredEvents())
+	existing, err := readCatalog(dir)
+	if err != nil {
+		return err
+	}
+	if changes := compareCatalog(existing, documents); len(changes) > 0 {
+		return &IncompatibleError{Changes: changes}
+	}
+
+	var outdated []string
+	for _, document := range documents {
+		data, err := encode(document.Schema)
+		if err != nil {
+			return err
+		}
+		current, ok := existing[document.Path()]
+		if !ok || !bytes.Equal(current.data, data) {
+			outdated = append(outdated, document.Path())
+		}
+	}
+	if len(outdated) > 0 {
+		return fmt.Errorf("%w: %s", ErrOutdated, strings.Join(outdated, ", "))
+	}
+	return nil
+}
+
+// Main runs the catalog generator as a command, for use from go:generate. The
+// program must register its events and upcasters before calling Main. Flags:
+//
+//	-dir    catalog directory (default "schemas")
+//	-lock   event shape lockfile to verify and update (optional)
+//	-check  verify the catalog and lockfile instead of writing them
+//	-force  write even when changes are incompatible
+func Main() {
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ExitOnError)
+	dir := flags.String("dir", "schemas", "catalog directory")
+	lock := flags.String("lock", "", "event shape lockfile to verify and update")
+	check := flags.Bool("check", false, "verify the catalog and lockfile instead of writing them")
+	force := flags.Bool("force", false, "write even when changes are incompatible")
+	_ = flags.Parse(os.Args[1:])
+
+	var opts []Option
+	if *force {
+		opts = append(opts, Force())
+	}
+	if err := run(*dir, *lock, *check, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run checks or writes the catalog before the lockfile, so a catalog write that
+// fails, for example on incompatible changes, leaves the lockfile untouched.
+func run(dir, lock string, check bool, opts []Option) error {
+	if check {
+		if err := Check(dir); err != nil {
+			return err
+		}
+		if lock != "" {
+			return CheckLock(lock)
+		}
+		return nil
+	}
+
+	if err := Write(dir, opts...); err != nil {
+		return err
+	}
+	if lock != "" {
+		return WriteLock(lock, opts...)
+	}
+	return nil
+}
+
+type catalogFile struct {
+	data   []byte
+	schema *Schema
+}
+
+// readCatalog loads every document under dir, keyed by slash-separated path.
+// A missing directory is an empty catalog.
+func readCatalog(dir string) (map[string]catalogFile, error) {
+	files := make(map[string]catalogFile)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == dir {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, schemaSuffix) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var schema Schema
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return fmt.Errorf("read schema %s: %w", path, err)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = catalogFile{data: data, schema: &schema}
+		return nil
+	})
+	return files, err
+}
+
+func compareCatalog(existing map[string]catalogFile, documents []Document) []Change {
+	generated := make(map[string]*Schema, len(documents))
+	for _, document := range documents {
+		generated[document.Path()] = document.Schema
+	}
+
+	var changes []Change
+	for _, path := range slices.Sorted(maps.Keys(existing)) {
+		next, ok := generated[path]
+		if !ok {
+			changes = append(changes, Change{Path: path, Message: "event schema removed"})
+			continue
+		}
+		for _, change := range Compare(existing[path].schema, next) {
+			change.Path = path + "#" + change.Path
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func encode(schema *Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// fileName replaces characters that are unsafe in file names with underscores.
+func fileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,36 @@
+package esschema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldNotWriteLockfileWhenCatalogWriteFails(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	lock := filepath.Join(t.TempDir(), "events.lock.json")
+	require.NoError(t, Write(dir))
+	path := filepath.Join(dir, "orders", "orders___order_placed.schema.json")
+	var previous Schema
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &previous))
+	previous.Properties["payload"].Properties["coupon"] = &Schema{Type: "string"}
+	data, err = json.Marshal(previous)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	// Act
+	err = run(dir, lock, false, nil)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrIncompatible)
+	assert.NoFileExists(t, lock)
+	require.NoError(t, run(dir, lock, false, []Option{Force()}))
+	assert.NoError(t, run(dir, lock, true, nil))
+}
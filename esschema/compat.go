@@ -0,0 +1,132 @@
+package esschema
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Change is one difference between two versions of a schema that breaks
+// consumers of the old version.
+type Change struct {
+	// Path locates the change, for example "dummy/dummy_created.schema.json#/payload/Name".
+	Path    string
+	Message string
+}
+
+func (c Change) String() string {
+	return c.Path + ": " + c.Message
+}
+
+// Compare returns the changes in next that break consumers written against prev.
+// Consumers tolerate new properties, new optional fields, and values that are no
+// longer null; removing or retyping a property, making a required property
+// optional, or allowing null where it was not allowed are breaking.
+func Compare(prev, next *Schema) []Change {
+	c := comparer{prevDefs: prev.Defs, nextDefs: next.Defs, seen: make(map[[2]string]bool)}
+	c.compare("", prev, next)
+	return c.changes
+}
+
+type comparer struct {
+	prevDefs, nextDefs map[string]*Schema
+	seen               map[[2]string]bool
+	changes            []Change
+}
+
+func (c *comparer) report(path, format string, args ...any) {
+	if path == "" {
+		path = "/"
+	}
+	c.changes = append(c.changes, Change{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *comparer) compare(path string, prev, next *Schema) {
+	if prev.Ref != "" && next.Ref != "" {
+		key := [2]string{prev.Ref, next.Ref}
+		if c.seen[key] {
+			return
+		}
+		c.seen[key] = true
+	}
+	prev, next = resolve(prev, c.prevDefs), resolve(next, c.nextDefs)
+	if prev == nil || next == nil {
+		return
+	}
+
+	prevBase, prevNullable := splitNullable(prev)
+	nextBase, nextNullable := splitNullable(next)
+	if nextNullable && !prevNullable {
+		c.report(path, "may now be null")
+	}
+	prev, next = resolve(prevBase, c.prevDefs), resolve(nextBase, c.nextDefs)
+	if isAny(prev) || isAny(next) {
+		return
+	}
+
+	if prev.Type != next.Type {
+		c.report(path, "type changed from %s to %s", describe(prev), describe(next))
+		return
+	}
+	if prev.Format != next.Format && prev.Format != "" {
+		c.report(path, "format changed from %q to %q", prev.Format, next.Format)
+	}
+	if prev.ContentEncoding != next.ContentEncoding {
+		c.report(path, "content encoding changed from %q to %q", prev.ContentEncoding, next.ContentEncoding)
+	}
+	if !reflect.DeepEqual(prev.Const, next.Const) {
+		c.report(path, "constant changed from %v to %v", prev.Const, next.Const)
+	}
+	if prev.Minimum != nil && (next.Minimum == nil || *next.Minimum < *prev.Minimum) {
+		c.report(path, "minimum was lowered")
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(prev.Properties)) {
+		property := path + "/" + name
+		nextProperty, ok := next.Properties[name]
+		if !ok {
+			c.report(property, "property removed")
+			continue
+		}
+		if slices.Contains(prev.Required, name) && !slices.Contains(next.Required, name) {
+			c.report(property, "property is no longer required")
+		}
+		c.compare(property, prev.Properties[name], nextProperty)
+	}
+	if prev.Items != nil && next.Items != nil {
+		c.compare(path+"/items", prev.Items, next.Items)
+	}
+	if prev.AdditionalProperties != nil && next.AdditionalProperties != nil {
+		c.compare(path+"/additionalProperties", prev.AdditionalProperties, next.AdditionalProperties)
+	}
+}
+
+// resolve follows a local $ref into defs.
+func resolve(schema *Schema, defs map[string]*Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+	}
+	return schema
+}
+
+// splitNullable unwraps the anyOf [T, null] form the generator uses for
+// pointers and slices.
+func splitNullable(schema *Schema) (*Schema, bool) {
+	if len(schema.AnyOf) == 2 && schema.AnyOf[1].Type == "null" {
+		return schema.AnyOf[0], true
+	}
+	return schema, false
+}
+
+func isAny(schema *Schema) bool {
+	return schema.Type == "" && len(schema.AnyOf) == 0
+}
+
+func describe(schema *Schema) string {
+	if schema.Type == "" {
+		return "a union"
+	}
+	return schema.Type
+}
@@ -0,0 +1,220 @@
+package esschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+
+	"github.com/fgrzl/es"
+)
+
+// LockfileVersion is the format version written to lockfiles.
+const LockfileVersion = 1
+
+// Lockfile records the shape of every registered event so later code can be
+// checked against the events already persisted.
+type Lockfile struct {
+	Version int                   `json:"version"`
+	Events  map[string]EventShape `json:"events"`
+}
+
+// EventShape is the JSON shape of one event payload.
+type EventShape struct {
+	// Type is the Go type registered for the discriminator.
+	Type string `json:"type"`
+	// Fields maps each JSON field path to its type. Nested fields use dotted
+	// paths, array elements "[]" and map values "{}", for example "lines[].sku".
+	Fields map[string]string `json:"fields"`
+}
+
+// Snapshot records the shapes of the given registrations.
+func Snapshot(registrations []es.EventRegistration) Lockfile {
+	lock := Lockfile{Version: LockfileVersion, Events: make(map[string]EventShape, len(registrations))}
+	for _, registration := range registrations {
+		eventType := registration.Type
+		for eventType.Kind() == reflect.Pointer {
+			eventType = eventType.Elem()
+		}
+
+		fields := make(map[string]string)
+		seen := map[reflect.Type]bool{eventType: true}
+		for _, field := range jsonFields(eventType) {
+			if field.name != "metadata" {
+				shapeOf(field.typ, field.name, field.asString, fields, seen)
+			}
+		}
+		lock.Events[registration.Discriminator] = EventShape{Type: eventType.PkgPath() + "." + eventType.Name(), Fields: fields}
+	}
+	return lock
+}
+
+// shapeOf records the type of the value at path and of anything nested in it.
+func shapeOf(t reflect.Type, path string, asString bool, fields map[string]string, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch scalar := scalarShape(t); {
+	case asString:
+		fields[path] = "string"
+	case scalar != "":
+		fields[path] = scalar
+	case t.Kind() == reflect.Struct:
+		fields[path] = "object"
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		for _, field := range jsonFields(t) {
+			shapeOf(field.typ, path+"."+field.name, field.asString, fields, seen)
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		fields[path] = "bytes"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		fields[path] = "array"
+		shapeOf(t.Elem(), path+"[]", false, fields, seen)
+	case t.Kind() == reflect.Map:
+		fields[path] = "map"
+		shapeOf(t.Elem(), path+"{}", false, fields, seen)
+	default:
+		fields[path] = "any"
+	}
+}
+
+// scalarShape names the JSON type of a type encoded as a single value, or returns
+// an empty string for structs, slices, arrays, and maps.
+func scalarShape(t reflect.Type) string {
+	switch t {
+	case uuidType:
+		return "uuid"
+	case timeType:
+		return "date-time"
+	case rawMessageType:
+		return "any"
+	}
+	if implements(t, jsonMarshalerType) {
+		return "json:" + t.String()
+	}
+	if implements(t, textMarshalerType) {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	}
+	return ""
+}
+
+// DiffLockfile returns the changes between a locked snapshot and the current one
+// that would break replay of persisted events: removed fields, changed field
+// types, and removed or renamed discriminators. Changes to a discriminator with a
+// registered es.Upcaster are assumed to be handled by it and are not reported.
+func DiffLockfile(locked, current Lockfile) []Change {
+	var changes []Change
+	for _, discriminator := range slices.Sorted(maps.Keys(locked.Events)) {
+		if _, ok := es.LookupUpcaster(discriminator); ok {
+			continue
+		}
+
+		prev := locked.Events[discriminator]
+		next, ok := current.Events[discriminator]
+		if !ok {
+			message := "event removed without an upcaster"
+			if renamed := findEventType(current, prev.Type); renamed != "" {
+				message = fmt.Sprintf("discriminator renamed to %s without an upcaster", renamed)
+			}
+			changes = append(changes, Change{Path: discriminator, Message: message})
+			continue
+		}
+
+		for _, field := range slices.Sorted(maps.Keys(prev.Fields)) {
+			nextType, ok := next.Fields[field]
+			switch {
+			case !ok:
+				changes = append(changes, Change{Path: discriminator + "#" + field, Message: "field removed without an upcaster"})
+			case nextType != prev.Fields[field]:
+				changes = append(changes, Change{
+					Path:    discriminator + "#" + field,
+					Message: fmt.Sprintf("field type changed from %s to %s without an upcaster", prev.Fields[field], nextType),
+				})
+			}
+		}
+	}
+	return changes
+}
+
+// findEventType returns a current discriminator registered for the Go type.
+func findEventType(lock Lockfile, eventType string) string {
+	for _, discriminator := range slices.Sorted(maps.Keys(lock.Events)) {
+		if lock.Events[discriminator].Type == eventType {
+			return discriminator
+		}
+	}
+	return ""
+}
+
+// ReadLockfile reads a lockfile. A missing file is an empty lockfile.
+func ReadLockfile(path string) (Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Lockfile{Version: LockfileVersion, Events: map[string]EventShape{}}, nil
+	}
+	if err != nil {
+		return Lockfile{}, err
+	}
+
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return Lockfile{}, fmt.Errorf("read lockfile %s: %w", path, err)
+	}
+	if lock.Version != LockfileVersion {
+		return Lockfile{}, fmt.Errorf("read lockfile %s: unsupported version %d", path, lock.Version)
+	}
+	return lock, nil
+}
+
+// CheckLock compares the registered events with the lockfile at path. It returns
+// an *IncompatibleError for changes that break replay of persisted events.
+func CheckLock(path string) error {
+	locked, err := ReadLockfile(path)
+	if err != nil {
+		return err
+	}
+	if changes := DiffLockfile(locked, Snapshot(es.RegisteredEvents())); len(changes) > 0 {
+		return &IncompatibleError{Changes: changes}
+	}
+	return nil
+}
+
+// WriteLock records the registered events in the lockfile at path. It writes
+// nothing and returns an *IncompatibleError when CheckLock would fail, unless
+// Force is given.
+func WriteLock(path string, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := CheckLock(path); err != nil && (!o.force || !errors.Is(err, ErrIncompatible)) {
+		return err
+	}
+
+	data, err := json.MarshalIndent(Snapshot(es.RegisteredEvents()), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
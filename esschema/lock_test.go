@@ -0,0 +1,110 @@
+package esschema_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fgrzl/es"
+	"github.com/fgrzl/es/esschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSnapshotEventShapes(t *testing.T) {
+	// Act
+	lock := esschema.Snapshot([]es.EventRegistration{orderPlacedRegistration(t)})
+
+	// Assert
+	shape := lock.Events["orders://order_placed"]
+	assert.Equal(t, "github.com/fgrzl/es/esschema_test.OrderPlaced", shape.Type)
+	assert.Equal(t, map[string]string{
+		"order_id":        "uuid",
+		"total":           "integer",
+		"lines":           "array",
+		"lines[]":         "string",
+		"shipping":        "object",
+		"shipping.street": "string",
+		"shipping.city":   "string",
+		"billing":         "object",
+		"billing.street":  "string",
+		"billing.city":    "string",
+		"notes":           "map",
+		"notes{}":         "string",
+		"receipt":         "bytes",
+	}, shape.Fields)
+}
+
+func TestShouldReportBreakingShapeChanges(t *testing.T) {
+	// Arrange
+	locked := esschema.Lockfile{Version: esschema.LockfileVersion, Events: map[string]esschema.EventShape{
+		"customer_created": {Type: "example.CustomerCreated", Fields: map[string]string{"name": "string"}},
+		"order_placed":     {Type: "example.OrderPlaced", Fields: map[string]string{"total": "integer", "coupon": "string"}},
+		"order_shipped":    {Type: "example.OrderShipped", Fields: map[string]string{}},
+	}}
+	current := esschema.Lockfile{Version: esschema.LockfileVersion, Events: map[string]esschema.EventShape{
+		"customer_registered": {Type: "example.CustomerCreated", Fields: map[string]string{"name": "string"}},
+		"order_placed":        {Type: "example.OrderPlaced", Fields: map[string]string{"total": "string", "notes": "string"}},
+	}}
+
+	// Act
+	changes := esschema.DiffLockfile(locked, current)
+
+	// Assert
+	assert.Equal(t, []esschema.Change{
+		{Path: "customer_created", Message: "discriminator renamed to customer_registered without an upcaster"},
+		{Path: "order_placed#coupon", Message: "field removed without an upcaster"},
+		{Path: "order_placed#total", Message: "field type changed from integer to string without an upcaster"},
+		{Path: "order_shipped", Message: "event removed without an upcaster"},
+	}, changes)
+}
+
+func TestShouldAcceptShapeChangesCoveredByUpcaster(t *testing.T) {
+	// Arrange
+	es.RegisterUpcaster("invoice_created", func(e es.Envelope) (es.Envelope, error) {
+		e.Discriminator = "invoice_issued"
+		return e, nil
+	})
+	locked := esschema.Lockfile{Version: esschema.LockfileVersion, Events: map[string]esschema.EventShape{
+		"invoice_created": {Type: "example.Invoice", Fields: map[string]string{"total": "integer"}},
+	}}
+	current := esschema.Lockfile{Version: esschema.LockfileVersion, Events: map[string]esschema.EventShape{
+		"invoice_issued": {Type: "example.Invoice", Fields: map[string]string{"total": "integer"}},
+	}}
+
+	// Act
+	changes := esschema.DiffLockfile(locked, current)
+
+	// Assert
+	assert.Empty(t, changes)
+}
+
+func TestShouldWriteAndCheckLockfile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "events.lock.json")
+
+	// Act
+	err := esschema.WriteLock(path)
+
+	// Assert
+	require.NoError(t, err)
+	assert.NoError(t, esschema.CheckLock(path))
+	lock, err := esschema.ReadLockfile(path)
+	require.NoError(t, err)
+	assert.Contains(t, lock.Events, "orders://order_placed")
+}
+
+func TestShouldRefuseToRelockBreakingChangesUnlessForced(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "events.lock.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":1,"events":{"order_cancelled":{"type":"example.OrderCancelled","fields":{}}}}`), 0o644))
+
+	// Act
+	err := esschema.WriteLock(path)
+
+	// Assert
+	assert.ErrorIs(t, err, esschema.ErrIncompatible)
+	assert.ErrorContains(t, err, "order_cancelled: event removed without an upcaster")
+	require.NoError(t, esschema.WriteLock(path, esschema.Force()))
+	assert.NoError(t, esschema.CheckLock(path))
+}
@@ -0,0 +1,264 @@
+// Package esschema publishes the contract of events registered with
+// github.com/fgrzl/es as JSON Schema documents.
+//
+// Each registered discriminator gets one document describing its envelope: the
+// discriminator, the standard event metadata, and the event payload. Documents
+// are grouped by the areas the event reports from GetAreas.
+//
+//	//go:generate go run ./cmd/eventschemas -dir ../../schemas
+//
+//	func main() {
+//		events.Register() // calls es.RegisterEvent for every event type
+//		esschema.Main()
+//	}
+package esschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fgrzl/es"
+	"github.com/google/uuid"
+)
+
+// Dialect is the JSON Schema dialect of generated documents.
+const Dialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document or subschema. Only the keywords the generator
+// emits are modelled.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Minimum              *int64             `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+	// Areas lists the event's areas. It is an annotation, not a validation keyword.
+	Areas []string `json:"x-areas,omitempty"`
+}
+
+// Generate returns the envelope schema of a registered event.
+func Generate(registration es.EventRegistration) *Schema {
+	g := &generator{defs: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+	metadata := g.schemaFor(reflect.TypeFor[es.EventMetadata]())
+
+	payloadType := registration.Type
+	for payloadType.Kind() == reflect.Pointer {
+		payloadType = payloadType.Elem()
+	}
+	payload := g.structSchema(payloadType)
+	delete(payload.Properties, "metadata")
+	payload.Required = removeString(payload.Required, "metadata")
+	payload.Title = payloadType.Name()
+
+	return &Schema{
+		Schema: Dialect,
+		Title:  registration.Discriminator,
+		Type:   "object",
+		Areas:  registration.Areas,
+		Properties: map[string]*Schema{
+			"discriminator": {Type: "string", Const: registration.Discriminator},
+			"metadata":      metadata,
+			"payload":       payload,
+			"encoding":      {Type: "string"},
+			"content_type":  {Type: "string"},
+		},
+		Required: []string{"discriminator", "metadata", "payload"},
+		Defs:     g.defs,
+	}
+}
+
+// generator converts Go types to schemas following encoding/json rules. Named
+// struct types other than the payload itself are emitted once under $defs.
+type generator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+var (
+	uuidType          = reflect.TypeFor[uuid.UUID]()
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	switch t {
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	if implements(t, jsonMarshalerType) {
+		return &Schema{Description: "custom JSON encoding of " + t.String()}
+	}
+	if implements(t, textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := int64(0)
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Pointer:
+		return &Schema{AnyOf: []*Schema{g.schemaFor(t.Elem()), {Type: "null"}}}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{AnyOf: []*Schema{{Type: "array", Items: g.schemaFor(t.Elem())}, {Type: "null"}}}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/$defs/" + g.define(t)}
+	}
+	// Interfaces, channels, and functions carry no static contract.
+	return &Schema{}
+}
+
+// define registers a named struct type under $defs and returns its name.
+func (g *generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := g.defs[name]; taken {
+		name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + name
+	}
+	g.names[t] = name
+	g.defs[name] = &Schema{} // placeholder for recursive types
+	*g.defs[name] = *g.structSchema(t)
+	return name
+}
+
+// structSchema describes a struct's JSON object. Fields without omitempty are
+// required because encoding/json always writes them.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range jsonFields(t) {
+		schema.Properties[field.name] = g.schemaFor(field.typ)
+		if field.asString {
+			schema.Properties[field.name] = &Schema{Type: "string"}
+		}
+		if !field.omitEmpty {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+	return schema
+}
+
+type jsonField struct {
+	name      string
+	typ       reflect.Type
+	omitEmpty bool
+	asString  bool
+	depth     int
+}
+
+// jsonFields lists the fields encoding/json writes for a struct, flattening
+// embedded structs. Shallower fields win over embedded fields with the same name.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	index := make(map[string]int)
+	var walk func(reflect.Type, int)
+	walk = func(t reflect.Type, depth int) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+
+			fieldType := field.Type
+			if field.Anonymous && name == "" {
+				embedded := fieldType
+				if embedded.Kind() == reflect.Pointer {
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct {
+					walk(embedded, depth+1)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			candidate := jsonField{
+				name:      name,
+				typ:       fieldType,
+				omitEmpty: hasOption(options, "omitempty") || hasOption(options, "omitzero"),
+				asString:  hasOption(options, "string"),
+				depth:     depth,
+			}
+			if existing, ok := index[name]; ok {
+				if fields[existing].depth > depth {
+					fields[existing] = candidate
+				}
+				continue
+			}
+			index[name] = len(fields)
+			fields = append(fields, candidate)
+		}
+	}
+	walk(t, 0)
+	return fields
+}
+
+func hasOption(options, option string) bool {
+	for options != "" {
+		var current string
+		current, options, _ = strings.Cut(options, ",")
+		if current == option {
+			return true
+		}
+	}
+	return false
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func removeString(values []string, value string) []string {
+	result := values[:0]
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
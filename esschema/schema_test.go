@@ -0,0 +1,170 @@
+package esschema_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fgrzl/es"
+	"github.com/fgrzl/es/esschema"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Address struct {
+	Street string `json:"street"`
+	City   string `json:"city,omitempty"`
+}
+
+type OrderPlaced struct {
+	es.DomainEventBase
+	OrderID  uuid.UUID         `json:"order_id"`
+	Total    uint64            `json:"total"`
+	Lines    []string          `json:"lines"`
+	Shipping *Address          `json:"shipping,omitempty"`
+	Billing  Address           `json:"billing"`
+	Notes    map[string]string `json:"notes"`
+	Receipt  []byte            `json:"receipt"`
+	internal string
+}
+
+func (e *OrderPlaced) GetDiscriminator() string { return "orders://order_placed" }
+func (e *OrderPlaced) GetAreas() []string       { return []string{"orders", "billing"} }
+func (e *OrderPlaced) GetSpaces() []string      { return e.GetAreas() }
+
+func init() {
+	es.RegisterEvent[*OrderPlaced]()
+}
+
+func orderPlacedRegistration(t *testing.T) es.EventRegistration {
+	registration, ok := es.LookupEvent("orders://order_placed")
+	require.True(t, ok)
+	return registration
+}
+
+func TestShouldGenerateEnvelopeSchemaForRegisteredEvent(t *testing.T) {
+	// Act
+	schema := esschema.Generate(orderPlacedRegistration(t))
+
+	// Assert
+	assert.Equal(t, esschema.Dialect, schema.Schema)
+	assert.Equal(t, []string{"orders", "billing"}, schema.Areas)
+	assert.Equal(t, "orders://order_placed", schema.Properties["discriminator"].Const)
+	assert.Equal(t, "#/$defs/EventMetadata", schema.Properties["metadata"].Ref)
+	assert.Contains(t, schema.Defs, "Entity")
+
+	payload := schema.Properties["payload"]
+	assert.NotContains(t, payload.Properties, "metadata")
+	assert.NotContains(t, payload.Properties, "internal")
+	assert.Equal(t, "uuid", payload.Properties["order_id"].Format)
+	assert.Equal(t, int64(0), *payload.Properties["total"].Minimum)
+	assert.Equal(t, "base64", payload.Properties["receipt"].ContentEncoding)
+	assert.Equal(t, "#/$defs/Address", payload.Properties["billing"].Ref)
+	assert.ElementsMatch(t, []string{"order_id", "total", "lines", "billing", "notes", "receipt"}, payload.Required)
+	assert.Equal(t, []string{"street"}, schema.Defs["Address"].Required)
+}
+
+func TestShouldGroupCatalogByArea(t *testing.T) {
+	// Act
+	documents := esschema.Catalog([]es.EventRegistration{orderPlacedRegistration(t)})
+
+	// Assert
+	require.Len(t, documents, 2)
+	assert.Equal(t, "billing/orders___order_placed.schema.json", documents[0].Path())
+	assert.Equal(t, "orders/orders___order_placed.schema.json", documents[1].Path())
+}
+
+func TestShouldWriteAndCheckCatalog(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+
+	// Act
+	err := esschema.Write(dir)
+
+	// Assert
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "orders", "orders___order_placed.schema.json"))
+	assert.NoError(t, esschema.Check(dir))
+}
+
+func TestShouldReportOutdatedCatalog(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+
+	// Act
+	err := esschema.Check(dir)
+
+	// Assert
+	assert.ErrorIs(t, err, esschema.ErrOutdated)
+}
+
+func TestShouldRefuseIncompatibleCatalogChangesUnlessForced(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	require.NoError(t, esschema.Write(dir))
+	path := filepath.Join(dir, "orders", "orders___order_placed.schema.json")
+	var previous esschema.Schema
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &previous))
+	previous.Properties["payload"].Properties["coupon"] = &esschema.Schema{Type: "string"}
+	previous.Properties["payload"].Properties["total"] = &esschema.Schema{Type: "string"}
+	data, err = json.Marshal(previous)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	// Act
+	err = esschema.Write(dir)
+
+	// Assert
+	var incompatible *esschema.IncompatibleError
+	require.ErrorAs(t, err, &incompatible)
+	assert.ErrorIs(t, err, esschema.ErrIncompatible)
+	assert.ElementsMatch(t, []esschema.Change{
+		{Path: "orders/orders___order_placed.schema.json#/payload/coupon", Message: "property removed"},
+		{Path: "orders/orders___order_placed.schema.json#/payload/total", Message: "type changed from string to integer"},
+	}, incompatible.Changes)
+	assert.NoError(t, esschema.Write(dir, esschema.Force()))
+	assert.NoError(t, esschema.Check(dir))
+}
+
+func TestShouldAllowAddingOptionalProperties(t *testing.T) {
+	// Arrange
+	prev := &esschema.Schema{Type: "object", Properties: map[string]*esschema.Schema{
+		"name": {Type: "string"},
+	}, Required: []string{"name"}}
+	next := &esschema.Schema{Type: "object", Properties: map[string]*esschema.Schema{
+		"name":  {Type: "string"},
+		"email": {Type: "string"},
+	}, Required: []string{"name"}}
+
+	// Act
+	changes := esschema.Compare(prev, next)
+
+	// Assert
+	assert.Empty(t, changes)
+}
+
+func TestShouldReportBreakingNullabilityAndRequiredChanges(t *testing.T) {
+	// Arrange
+	prev := &esschema.Schema{Type: "object", Properties: map[string]*esschema.Schema{
+		"name": {Type: "string"},
+		"tags": {Type: "array", Items: &esschema.Schema{Type: "string"}},
+	}, Required: []string{"name", "tags"}}
+	next := &esschema.Schema{Type: "object", Properties: map[string]*esschema.Schema{
+		"name": {Type: "string"},
+		"tags": {AnyOf: []*esschema.Schema{{Type: "array", Items: &esschema.Schema{Type: "integer"}}, {Type: "null"}}},
+	}, Required: []string{"tags"}}
+
+	// Act
+	changes := esschema.Compare(prev, next)
+
+	// Assert
+	assert.Equal(t, []esschema.Change{
+		{Path: "/name", Message: "property is no longer required"},
+		{Path: "/tags", Message: "may now be null"},
+		{Path: "/tags/items", Message: "type changed from string to integer"},
+	}, changes)
+}
@@ -0,0 +1,233 @@
+// Package estest provides a Given/When/Then harness for testing aggregates
+// built on github.com/fgrzl/es.
+//
+//	estest.For(t, NewCat(id)).
+//		Given(&CatRenamed{Name: "Tom"}).
+//		When(func(c *Cat) error { return c.Adopt() }).
+//		Then(&CatAdopted{})
+//
+// Expected events are compared by discriminator and payload; event metadata
+// (IDs, timestamps, correlation, causation, sequence) is ignored.
+package estest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/fgrzl/es"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Scenario drives a single aggregate through history, one command, and expectations.
+type Scenario[A es.Aggregate] struct {
+	t   testing.TB
+	agg A
+	// target is agg held as an interface value; aggregate methods are called
+	// through it rather than through the type parameter.
+	target    es.Aggregate
+	whenRan   bool
+	err       error
+	panicked  bool
+	recovered any
+}
+
+// For starts a scenario for the given aggregate. The aggregate should be freshly
+// constructed with its handlers registered.
+func For[A es.Aggregate](t testing.TB, agg A) *Scenario[A] {
+	t.Helper()
+	return &Scenario[A]{t: t, agg: agg, target: agg}
+}
+
+// Aggregate returns the aggregate under test for additional assertions.
+func (s *Scenario[A]) Aggregate() A {
+	return s.agg
+}
+
+// Given replays history onto the aggregate through Aggregate.Load.
+// Events without metadata are stamped with the aggregate's entity and the next
+// committed sequence so handlers observe a realistic stream.
+func (s *Scenario[A]) Given(events ...es.DomainEvent) *Scenario[A] {
+	s.t.Helper()
+	if s.whenRan {
+		s.t.Fatalf("estest: Given must be called before When")
+	}
+
+	next := s.target.GetCommittedSequence()
+	for _, event := range events {
+		next++
+		event.SetMetadata(es.EventMetadata{
+			Entity:        s.target.GetEntity(),
+			EventID:       uuid.New(),
+			CorrelationID: s.target.GetCorrelationID(),
+			CausationID:   s.target.GetCausationID(),
+			Sequence:      next,
+		})
+	}
+
+	if err := s.target.Load(events); err != nil {
+		s.t.Fatalf("estest: Given failed to load history: %v", err)
+	}
+	return s
+}
+
+// When runs a command against the aggregate, capturing the returned error and any panic.
+func (s *Scenario[A]) When(command func(A) error) *Scenario[A] {
+	s.t.Helper()
+	if s.whenRan {
+		s.t.Fatalf("estest: When must only be called once per scenario")
+	}
+	s.whenRan = true
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.panicked = true
+				s.recovered = r
+			}
+		}()
+		s.err = command(s.agg)
+	}()
+	return s
+}
+
+// Then asserts that the command succeeded and raised exactly the expected events, in order.
+// Calling Then with no events asserts that nothing was raised.
+func (s *Scenario[A]) Then(expected ...es.DomainEvent) *Scenario[A] {
+	s.t.Helper()
+	s.requireSuccess()
+	assertEvents(s.t, "uncommitted events", expected, s.target.GetUncommittedEvents())
+	return s
+}
+
+// ThenAudits asserts that the command succeeded and staged exactly the expected audits, in order.
+func (s *Scenario[A]) ThenAudits(expected ...es.DomainEvent) *Scenario[A] {
+	s.t.Helper()
+	s.requireSuccess()
+
+	pending := s.target.GetPendingAudits()
+	actual := make([]es.DomainEvent, 0, len(pending))
+	for _, audit := range pending {
+		actual = append(actual, audit.Event)
+	}
+	assertEvents(s.t, "pending audits", expected, actual)
+	return s
+}
+
+// ThenError asserts that the command returned an error matching target with errors.Is.
+func (s *Scenario[A]) ThenError(target error) *Scenario[A] {
+	s.t.Helper()
+	s.requireRan()
+	if s.panicked {
+		s.t.Fatalf("estest: expected error %v but command panicked: %v", target, s.recovered)
+	}
+	if !errors.Is(s.err, target) {
+		s.t.Errorf("estest: expected error matching %v, got %v", target, s.err)
+	}
+	return s
+}
+
+// ThenPanics asserts that the command panicked, which the default aggregate
+// implementation does for wiring mistakes such as invalid event areas.
+func (s *Scenario[A]) ThenPanics() *Scenario[A] {
+	s.t.Helper()
+	s.requireRan()
+	if !s.panicked {
+		s.t.Errorf("estest: expected command to panic, returned %v", s.err)
+	}
+	return s
+}
+
+func (s *Scenario[A]) requireRan() {
+	s.t.Helper()
+	if !s.whenRan {
+		s.t.Fatalf("estest: When must be called before asserting outcomes")
+	}
+}
+
+func (s *Scenario[A]) requireSuccess() {
+	s.t.Helper()
+	s.requireRan()
+	if s.panicked {
+		s.t.Fatalf("estest: command panicked: %v", s.recovered)
+	}
+	if s.err != nil {
+		s.t.Fatalf("estest: command returned error: %v", s.err)
+	}
+}
+
+func assertEvents(t testing.TB, label string, expected, actual []es.DomainEvent) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Errorf("estest: expected %d %s, got %d: %s", len(expected), label, len(actual), describe(actual))
+		return
+	}
+
+	for i := range expected {
+		want, got := expected[i], actual[i]
+		if want.GetDiscriminator() != got.GetDiscriminator() {
+			t.Errorf("estest: %s[%d]: expected %s, got %s", label, i, want.GetDiscriminator(), got.GetDiscriminator())
+			continue
+		}
+		assert.Equal(t, withoutMetadata(want), withoutMetadata(got), "estest: %s[%d] payload mismatch", label, i)
+	}
+}
+
+func describe(events []es.DomainEvent) string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, event.GetDiscriminator())
+	}
+	return fmt.Sprint(names)
+}
+
+var metadataType = reflect.TypeFor[es.EventMetadata]()
+
+// withoutMetadata returns a copy of the event value with every EventMetadata
+// field cleared, so payloads can be compared without volatile identifiers.
+func withoutMetadata(event es.DomainEvent) any {
+	v := reflect.ValueOf(event)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return event
+		}
+		v = v.Elem()
+	}
+
+	clone := reflect.New(v.Type()).Elem()
+	clone.Set(v)
+	clearMetadata(clone)
+	return clone.Interface()
+}
+
+func clearMetadata(v reflect.Value) {
+	if v.Type() == metadataType {
+		v.SetZero()
+		return
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := range v.NumField() {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		switch {
+		case field.Type() == metadataType:
+			field.SetZero()
+		case field.Kind() == reflect.Struct && v.Type().Field(i).Anonymous:
+			clearMetadata(field)
+		case field.Kind() == reflect.Pointer && v.Type().Field(i).Anonymous && !field.IsNil() && field.Elem().Kind() == reflect.Struct:
+			copied := reflect.New(field.Elem().Type())
+			copied.Elem().Set(field.Elem())
+			clearMetadata(copied.Elem())
+			field.Set(copied)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package estest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fgrzl/es"
+	"github.com/fgrzl/es/estest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const areaLight = "light"
+
+var errAlreadyOn = errors.New("light already on")
+
+type LightInstalled struct {
+	es.DomainEventBase
+	Room string
+}
+
+func (e *LightInstalled) GetDiscriminator() string { return "light_installed" }
+func (e *LightInstalled) GetAreas() []string       { return []string{areaLight} }
+func (e *LightInstalled) GetSpaces() []string      { return e.GetAreas() }
+
+type LightSwitchedOn struct {
+	es.DomainEventBase
+	Room string
+}
+
+func (e *LightSwitchedOn) GetDiscriminator() string { return "light_switched_on" }
+func (e *LightSwitchedOn) GetAreas() []string       { return []string{areaLight} }
+func (e *LightSwitchedOn) GetSpaces() []string      { return e.GetAreas() }
+
+type LightInspected struct {
+	es.DomainEventBase
+	By string
+}
+
+func (e *LightInspected) GetDiscriminator() string { return "light_inspected" }
+func (e *LightInspected) GetAreas() []string       { return []string{areaLight} }
+func (e *LightInspected) GetSpaces() []string      { return e.GetAreas() }
+
+type ForeignEvent struct {
+	es.DomainEventBase
+}
+
+func (e *ForeignEvent) GetDiscriminator() string { return "foreign_event" }
+func (e *ForeignEvent) GetAreas() []string       { return []string{"elsewhere"} }
+func (e *ForeignEvent) GetSpaces() []string      { return e.GetAreas() }
+
+type Light struct {
+	es.Aggregate
+	room string
+	on   bool
+}
+
+func NewLight() *Light {
+	light := &Light{Aggregate: es.NewAggregate(context.Background(), areaLight, uuid.New())}
+	es.RegisterHandler(light, light.OnInstalled)
+	es.RegisterHandler(light, light.OnSwitchedOn)
+	return light
+}
+
+func (l *Light) SwitchOn(by string) error {
+	if l.on {
+		return fmt.Errorf("switch on %s: %w", l.room, errAlreadyOn)
+	}
+	if err := l.Audit(&LightInspected{By: by}); err != nil {
+		return err
+	}
+	return l.Raise(&LightSwitchedOn{Room: l.room})
+}
+
+func (l *Light) OnInstalled(e *LightInstalled)   { l.room = e.Room }
+func (l *Light) OnSwitchedOn(e *LightSwitchedOn) { l.on = true }
+
+func TestShouldPassWhenCommandRaisesExpectedEvents(t *testing.T) {
+	estest.For(t, NewLight()).
+		Given(&LightInstalled{Room: "kitchen"}).
+		When(func(l *Light) error { return l.SwitchOn("alice") }).
+		Then(&LightSwitchedOn{Room: "kitchen"}).
+		ThenAudits(&LightInspected{By: "alice"})
+}
+
+func TestShouldStampGivenEventsWithSequentialMetadata(t *testing.T) {
+	// Arrange
+	light := NewLight()
+
+	// Act
+	estest.For(t, light).Given(&LightInstalled{Room: "hall"}, &LightSwitchedOn{Room: "hall"})
+
+	// Assert
+	committed := light.GetCommittedEvents()
+	assert.Len(t, committed, 2)
+	assert.Equal(t, light.GetEntity(), committed[1].GetEntity())
+	assert.Equal(t, uint64(2), committed[1].GetSequence())
+	assert.Equal(t, uint64(2), light.GetCommittedSequence())
+}
+
+func TestShouldMatchErrorWithErrorsIs(t *testing.T) {
+	estest.For(t, NewLight()).
+		Given(&LightInstalled{Room: "kitchen"}, &LightSwitchedOn{Room: "kitchen"}).
+		When(func(l *Light) error { return l.SwitchOn("bob") }).
+		ThenError(errAlreadyOn)
+}
+
+func TestShouldDetectPanicFromInvalidWiring(t *testing.T) {
+	estest.For(t, NewLight()).
+		When(func(l *Light) error { return l.Raise(&ForeignEvent{}) }).
+		ThenPanics()
+}
+
+func TestShouldReportMismatchedPayload(t *testing.T) {
+	// Arrange
+	recorder := &recordingT{TB: t}
+
+	// Act
+	estest.For(recorder, NewLight()).
+		Given(&LightInstalled{Room: "kitchen"}).
+		When(func(l *Light) error { return l.SwitchOn("alice") }).
+		Then(&LightSwitchedOn{Room: "garage"})
+
+	// Assert
+	assert.True(t, recorder.failed)
+}
+
+func TestShouldReportUnexpectedEventCount(t *testing.T) {
+	// Arrange
+	recorder := &recordingT{TB: t}
+
+	// Act
+	estest.For(recorder, NewLight()).
+		When(func(l *Light) error { return l.SwitchOn("alice") }).
+		Then()
+
+	// Assert
+	assert.True(t, recorder.failed)
+}
+
+func TestShouldFailWhenExpectedEventsButCommandReturnedError(t *testing.T) {
+	// Arrange
+	recorder := &recordingT{TB: t}
+
+	// Act
+	assert.Panics(t, func() {
+		estest.For(recorder, NewLight()).
+			Given(&LightInstalled{Room: "kitchen"}, &LightSwitchedOn{Room: "kitchen"}).
+			When(func(l *Light) error { return l.SwitchOn("bob") }).
+			Then(&LightSwitchedOn{Room: "kitchen"})
+	})
+
+	// Assert
+	assert.True(t, recorder.failed)
+}
+
+// recordingT captures failures instead of failing the enclosing test.
+// Fatalf panics to stop the scenario the way t.FailNow would.
+type recordingT struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.failed = true
+}
+
+func (r *recordingT) Fatalf(format string, args ...any) {
+	r.failed = true
+	panic(fmt.Sprintf(format, args...))
+}
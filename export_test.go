@@ -0,0 +1,166 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStampedDummyEvents(entity Entity, count int) []DomainEvent {
+	events := newDummyEvents(entity, count)
+	for i, event := range events {
+		metadata := event.GetMetadata()
+		metadata.CorrelationID = uuid.New()
+		metadata.CausationID = uuid.New()
+		metadata.Timestamp = int64(1000 + i)
+		event.SetMetadata(metadata)
+	}
+	return events
+}
+
+func TestShouldRoundTripStoresThroughNDJSON(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	first := NewEntity(uuid.New(), AreaDummy)
+	second := NewTenantEntity(uuid.New(), uuid.New(), AreaDummy)
+	firstEvents := newStampedDummyEvents(first, 3)
+	secondEvents := newStampedDummyEvents(second, 2)
+	require.NoError(t, source.SaveEvents(ctx, first, firstEvents, 0))
+	require.NoError(t, source.SaveEvents(ctx, second, secondEvents, 0))
+	var dump bytes.Buffer
+	require.NoError(t, Export(ctx, source, &dump, ExportFilter{Entities: []Entity{first, second}}))
+	target := NewInMemoryEventStore()
+
+	// Act
+	stats, err := Import(ctx, &dump, target)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ImportStats{Streams: 2, Imported: 5}, stats)
+	loaded, err := target.LoadEvents(ctx, first, 0)
+	require.NoError(t, err)
+	assert.Equal(t, firstEvents, loaded)
+	loaded, err = target.LoadEvents(ctx, second, 0)
+	require.NoError(t, err)
+	assert.Equal(t, secondEvents, loaded)
+}
+
+func TestShouldRoundTripTruncatedStreamThroughNDJSON(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newStampedDummyEvents(entity, 6)
+	require.NoError(t, source.SaveEvents(ctx, entity, events[:5], 0))
+	require.NoError(t, source.(*InMemoryEventStore).TruncateStreamBefore(ctx, entity, 4))
+	var dump bytes.Buffer
+	require.NoError(t, Export(ctx, source, &dump, ExportFilter{Entities: []Entity{entity}}))
+	target := NewInMemoryEventStore()
+
+	// Act
+	stats, err := Import(ctx, &dump, target)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ImportStats{Streams: 1, Imported: 2}, stats)
+	loaded, err := target.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events[3:5], loaded)
+	assert.NoError(t, target.SaveEvents(ctx, entity, events[5:], 5))
+}
+
+func TestShouldRejectTruncatedStreamImportWithoutCopySupport(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, source.SaveEvents(ctx, entity, newStampedDummyEvents(entity, 5), 0))
+	require.NoError(t, source.(*InMemoryEventStore).TruncateStreamBefore(ctx, entity, 4))
+	var dump bytes.Buffer
+	require.NoError(t, Export(ctx, source, &dump, ExportFilter{Entities: []Entity{entity}}))
+
+	// Act
+	_, err := Import(ctx, &dump, basicStore{inner: NewInMemoryEventStore()})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestShouldResumeInterruptedImport(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newStampedDummyEvents(entity, 4)
+	require.NoError(t, source.SaveEvents(ctx, entity, events, 0))
+	var dump bytes.Buffer
+	require.NoError(t, Export(ctx, source, &dump, ExportFilter{Entities: []Entity{entity}}))
+	target := NewInMemoryEventStore()
+	require.NoError(t, target.SaveEvents(ctx, entity, events[:2], 0))
+
+	// Act
+	stats, err := Import(ctx, &dump, target)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ImportStats{Streams: 1, Imported: 2, Skipped: 2}, stats)
+	loaded, err := target.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events, loaded)
+}
+
+func TestShouldRejectSequenceGapsOnImport(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newStampedDummyEvents(entity, 3)
+	var dump bytes.Buffer
+	codec := NewJSONCodec()
+	for _, event := range []DomainEvent{events[0], events[2]} {
+		data, err := codec.Marshal(event)
+		require.NoError(t, err)
+		dump.Write(append(data, '\n'))
+	}
+	target := NewInMemoryEventStore()
+
+	// Act
+	stats, err := Import(ctx, &dump, target)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidSequence)
+	assert.ErrorContains(t, err, "expected sequence 2, got 3")
+	assert.Equal(t, 0, stats.Imported)
+}
+
+func TestShouldImportInterleavedStreams(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	first := NewEntity(uuid.New(), AreaDummy)
+	second := NewEntity(uuid.New(), AreaDummy)
+	firstEvents := newStampedDummyEvents(first, 2)
+	secondEvents := newStampedDummyEvents(second, 2)
+	var lines []string
+	codec := NewJSONCodec()
+	for _, event := range []DomainEvent{firstEvents[0], secondEvents[0], firstEvents[1], secondEvents[1]} {
+		data, err := codec.Marshal(event)
+		require.NoError(t, err)
+		lines = append(lines, string(data))
+	}
+	target := NewInMemoryEventStore()
+
+	// Act
+	stats, err := Import(ctx, strings.NewReader(strings.Join(lines, "\n")), target)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ImportStats{Streams: 2, Imported: 4}, stats)
+	version, _, err := StreamVersion(ctx, target, second)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), version)
+}
@@ -0,0 +1,295 @@
+package es
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+)
+
+// FaultCall describes a store call being considered by a Fault.
+type FaultCall struct {
+	Operation        string
+	Entity           Entity
+	Events           []DomainEvent
+	ExpectedSequence uint64
+	// Audit reports whether the call persists an audit batch stream (see IsAuditWrite).
+	Audit bool
+}
+
+// Fault is one entry of a FaultyStore schedule. A fault applies to the calls that
+// pass its filters (Operation, AuditOnly, Match); Call and Times pick which of
+// those calls it fires on.
+type Fault struct {
+	// Operation limits the fault to one StoreOperation* name. Empty matches every operation.
+	Operation string
+	// AuditOnly limits the fault to SaveEvents calls that persist audit batch streams.
+	AuditOnly bool
+	// Match further filters calls. Nil matches every call.
+	Match func(FaultCall) bool
+
+	// Call is the 1-based index, among matching calls, of the first call the fault
+	// fires on. Zero fires from the first matching call.
+	Call int
+	// Times is how many matching calls the fault fires on, starting at Call.
+	// Zero fires on one call when Call is set and on every call otherwise.
+	Times int
+
+	// Err is returned for the call. When AfterSuccess is set and Err is nil,
+	// ErrInjectedFault is returned.
+	Err error
+	// AfterSuccess performs the call on the wrapped store first and then returns Err,
+	// simulating a timeout or lost acknowledgement after a successful write.
+	AfterSuccess bool
+	// Delay is waited before the call. The wait ends early when the context is done.
+	Delay time.Duration
+	// DropContext calls the wrapped store with context.Background(), discarding
+	// the caller's deadline, cancellation, and values.
+	DropContext bool
+}
+
+// FailNthSave fails the n-th SaveEvents call (1-based) with err.
+func FailNthSave(n int, err error) Fault {
+	return Fault{Operation: StoreOperationSaveEvents, Call: n, Err: err}
+}
+
+// FailAuditSaves fails every SaveEvents call that persists an audit batch stream.
+func FailAuditSaves(err error) Fault {
+	return Fault{Operation: StoreOperationSaveEvents, AuditOnly: true, Err: err}
+}
+
+// SucceedThenFail lets the n-th SaveEvents call (1-based) reach the wrapped store
+// and then returns err, as if the acknowledgement was lost.
+func SucceedThenFail(n int, err error) Fault {
+	return Fault{Operation: StoreOperationSaveEvents, Call: n, Err: err, AfterSuccess: true}
+}
+
+// AddLatency delays every call to the given operation, or every call when operation is empty.
+func AddLatency(operation string, delay time.Duration) Fault {
+	return Fault{Operation: operation, Delay: delay}
+}
+
+// DropContext passes context.Background() to the wrapped store for every call to
+// the given operation, or every call when operation is empty.
+func DropContext(operation string) Fault {
+	return Fault{Operation: operation, DropContext: true}
+}
+
+// FaultyStore wraps a Store and injects failures, latency, and context loss according
+// to a schedule of Faults. It is intended for resilience tests, for example of the
+// partial audit/domain failures described in docs/audit_events.md.
+// It is safe for concurrent use.
+type FaultyStore struct {
+	ForwardingStore
+
+	mu     sync.Mutex
+	faults []*scheduledFault
+	calls  map[string]int
+}
+
+type scheduledFault struct {
+	Fault
+	matched int
+}
+
+// NewFaultyStore wraps store with the given fault schedule.
+func NewFaultyStore(store Store, faults ...Fault) *FaultyStore {
+	s := &FaultyStore{ForwardingStore: ForwardingStore{Next: store}, calls: make(map[string]int)}
+	s.Script(faults...)
+	return s
+}
+
+// WithFaults returns a StoreMiddleware that wraps a store in a FaultyStore.
+func WithFaults(faults ...Fault) StoreMiddleware {
+	return func(next Store) Store {
+		return NewFaultyStore(next, faults...)
+	}
+}
+
+// Script appends faults to the schedule.
+func (s *FaultyStore) Script(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, fault := range faults {
+		s.faults = append(s.faults, &scheduledFault{Fault: fault})
+	}
+}
+
+// Reset clears the schedule and call counts.
+func (s *FaultyStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+	s.calls = make(map[string]int)
+}
+
+// Calls returns how many calls to operation the store has received.
+func (s *FaultyStore) Calls(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[operation]
+}
+
+// injection is the combined effect of every fault firing on one call.
+type injection struct {
+	err          error
+	afterSuccess bool
+	delay        time.Duration
+	dropContext  bool
+}
+
+// plan counts the call and works out which faults fire on it.
+func (s *FaultyStore) plan(call FaultCall) injection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.calls == nil {
+		s.calls = make(map[string]int)
+	}
+	s.calls[call.Operation]++
+
+	var result injection
+	for _, fault := range s.faults {
+		if !fault.matches(call) {
+			continue
+		}
+		fault.matched++
+		if !fault.fires() {
+			continue
+		}
+
+		result.delay += fault.Delay
+		result.dropContext = result.dropContext || fault.DropContext
+		if result.err == nil && (fault.Err != nil || fault.AfterSuccess) {
+			result.err = fault.Err
+			result.afterSuccess = fault.AfterSuccess
+			if result.err == nil {
+				result.err = ErrInjectedFault
+			}
+		}
+	}
+	return result
+}
+
+func (f *scheduledFault) matches(call FaultCall) bool {
+	if f.Operation != "" && f.Operation != call.Operation {
+		return false
+	}
+	if f.AuditOnly && !call.Audit {
+		return false
+	}
+	return f.Match == nil || f.Match(call)
+}
+
+func (f *scheduledFault) fires() bool {
+	first := max(f.Call, 1)
+	if f.matched < first {
+		return false
+	}
+
+	times := f.Times
+	if times == 0 && f.Call > 0 {
+		times = 1
+	}
+	return times == 0 || f.matched < first+times
+}
+
+// begin applies delay and context loss and reports the error to return before
+// calling the wrapped store, if any.
+func (in injection) begin(ctx context.Context) (context.Context, error) {
+	if in.delay > 0 {
+		timer := time.NewTimer(in.delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx, ctx.Err()
+		}
+	}
+	if in.dropContext {
+		ctx = context.Background()
+	}
+	if in.err != nil && !in.afterSuccess {
+		return ctx, in.err
+	}
+	return ctx, nil
+}
+
+// end returns the error to report after the wrapped store returned err.
+func (in injection) end(err error) error {
+	if err == nil && in.afterSuccess {
+		return in.err
+	}
+	return err
+}
+
+// SaveEvents implements Store.SaveEvents.
+func (s *FaultyStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
+	in := s.plan(FaultCall{
+		Operation:        StoreOperationSaveEvents,
+		Entity:           entity,
+		Events:           events,
+		ExpectedSequence: expectedSequence,
+		Audit:            IsAuditWrite(ctx),
+	})
+
+	ctx, err := in.begin(ctx)
+	if err != nil {
+		return err
+	}
+	return in.end(s.Next.SaveEvents(ctx, entity, events, expectedSequence))
+}
+
+// LoadEvents implements Store.LoadEvents.
+func (s *FaultyStore) LoadEvents(ctx context.Context, entity Entity, minSequence uint64) ([]DomainEvent, error) {
+	in := s.plan(FaultCall{Operation: StoreOperationLoadEvents, Entity: entity})
+
+	ctx, err := in.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.Next.LoadEvents(ctx, entity, minSequence)
+	if err = in.end(err); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// LoadEventStream implements StreamingStore.LoadEventStream.
+func (s *FaultyStore) LoadEventStream(ctx context.Context, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error] {
+	return func(yield func(DomainEvent, error) bool) {
+		in := s.plan(FaultCall{Operation: StoreOperationLoadEventStream, Entity: entity})
+
+		ctx, err := in.begin(ctx)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for event, err := range StreamEvents(ctx, s.Next, entity, minSequence) {
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+		if err := in.end(nil); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// ReadStream implements StreamReader.ReadStream.
+func (s *FaultyStore) ReadStream(ctx context.Context, entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	in := s.plan(FaultCall{Operation: StoreOperationReadStream, Entity: entity})
+
+	ctx, err := in.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events, err := ReadStream(ctx, s.Next, entity, opts)
+	if err = in.end(err); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,154 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldFailOnlyTheNthSave(t *testing.T) {
+	// Arrange
+	injected := errors.New("disk full")
+	store := NewFaultyStore(NewInMemoryEventStore(), FailNthSave(2, injected))
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 3)
+
+	// Act
+	first := store.SaveEvents(ctx, entity, events[:1], 0)
+	second := store.SaveEvents(ctx, entity, events[1:2], 1)
+	third := store.SaveEvents(ctx, entity, events[1:2], 1)
+
+	// Assert
+	assert.NoError(t, first)
+	assert.ErrorIs(t, second, injected)
+	assert.NoError(t, third)
+	assert.Equal(t, 3, store.Calls(StoreOperationSaveEvents))
+}
+
+func TestShouldPersistAuditsWhenDomainAppendFails(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	injected := errors.New("domain append failed")
+	store := NewFaultyStore(NewInMemoryEventStore(), Fault{
+		Operation: StoreOperationSaveEvents,
+		Match:     func(call FaultCall) bool { return !call.Audit },
+		Err:       injected,
+	})
+	repo := NewRepository(store)
+	dummy := NewDummy()
+	require.NoError(t, dummy.LogAudit("attempt"))
+	require.NoError(t, dummy.Create("alice"))
+	auditEntity := dummy.GetPendingAudits()[0].Entity
+
+	// Act
+	err := repo.Save(ctx, dummy)
+
+	// Assert
+	assert.ErrorIs(t, err, injected)
+	audits, loadErr := store.LoadEvents(ctx, auditEntity, 0)
+	require.NoError(t, loadErr)
+	assert.Len(t, audits, 1)
+	assert.Empty(t, dummy.GetPendingAudits())
+	assert.Len(t, dummy.GetUncommittedEvents(), 1)
+}
+
+func TestShouldFailAuditWritesWithoutTouchingDomainStream(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	injected := errors.New("audit sink down")
+	store := NewFaultyStore(NewInMemoryEventStore(), FailAuditSaves(injected))
+	repo := NewRepository(store)
+	dummy := NewDummy()
+	require.NoError(t, dummy.LogAudit("attempt"))
+	require.NoError(t, dummy.Create("alice"))
+
+	// Act
+	err := repo.Save(ctx, dummy)
+
+	// Assert
+	assert.ErrorIs(t, err, injected)
+	domain, loadErr := store.LoadEvents(ctx, dummy.GetEntity(), 0)
+	require.NoError(t, loadErr)
+	assert.Empty(t, domain)
+	assert.Len(t, dummy.GetPendingAudits(), 1)
+}
+
+func TestShouldSurfaceConcurrencyOnRetryAfterLostAcknowledgement(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewFaultyStore(NewInMemoryEventStore(), SucceedThenFail(1, context.DeadlineExceeded))
+	repo := NewRepository(store)
+	dummy := NewDummy()
+	require.NoError(t, dummy.Create("alice"))
+
+	// Act
+	first := repo.Save(ctx, dummy)
+	retry := repo.Save(ctx, dummy)
+
+	// Assert
+	assert.ErrorIs(t, first, context.DeadlineExceeded)
+	assert.ErrorIs(t, retry, ErrConcurrency)
+	persisted, err := store.LoadEvents(ctx, dummy.GetEntity(), 0)
+	require.NoError(t, err)
+	assert.Len(t, persisted, 1)
+}
+
+func TestShouldAbortLatencyWhenContextIsDone(t *testing.T) {
+	// Arrange
+	store := NewFaultyStore(NewInMemoryEventStore(), AddLatency("", time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	entity := NewEntity(uuid.New(), AreaDummy)
+
+	// Act
+	_, err := store.LoadEvents(ctx, entity, 0)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestShouldDropContextBeforeCallingWrappedStore(t *testing.T) {
+	// Arrange
+	var seen context.Context
+	inner := StoreRecorderFunc(func(ctx context.Context, call StoreCall) { seen = ctx })
+	store := NewFaultyStore(RecordingStore(inner)(NewInMemoryEventStore()), DropContext(StoreOperationSaveEvents))
+	ctx := ContextWithTracing(context.Background(), uuid.New(), uuid.New())
+	entity := NewEntity(uuid.New(), AreaDummy)
+
+	// Act
+	err := store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, GetCorrelationID(seen))
+}
+
+func TestShouldInjectDefaultErrorOnStreamAfterSuccess(t *testing.T) {
+	// Arrange
+	store := NewFaultyStore(NewInMemoryEventStore(), Fault{Operation: StoreOperationLoadEventStream, AfterSuccess: true})
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 2), 0))
+
+	// Act
+	var events int
+	var streamErr error
+	for event, err := range store.LoadEventStream(ctx, entity, 0) {
+		if err != nil {
+			streamErr = err
+			continue
+		}
+		assert.NotNil(t, event)
+		events++
+	}
+
+	// Assert
+	assert.Equal(t, 2, events)
+	assert.ErrorIs(t, streamErr, ErrInjectedFault)
+}
@@ -3,66 +3,486 @@ package es
 import (
 	"context"
 	"fmt"
+	"hash/maphash"
+	"iter"
+	"slices"
 	"sync"
 )
 
+// inMemoryStreamPageSize bounds how many events LoadEventStream copies out of the
+// store per lock acquisition.
+const inMemoryStreamPageSize = 256
+
+// InMemoryStoreOption configures an InMemoryEventStore.
+type InMemoryStoreOption func(*InMemoryEventStore)
+
+// WithStoreClock sets the clock the in-memory store uses for stream timestamps
+// and MaxAge retention.
+func WithStoreClock(clock Clock) InMemoryStoreOption {
+	return func(s *InMemoryEventStore) {
+		s.clock = clock
+	}
+}
+
+// WithStoreCodec makes the in-memory store keep each event as the bytes produced by
+// codec instead of the caller's event value, as a persistent store would. Reads
+// decode fresh events, so serialization problems surface in tests and callers
+// cannot mutate stored events.
+func WithStoreCodec(codec EventCodec) InMemoryStoreOption {
+	return func(s *InMemoryEventStore) {
+		s.codec = codec
+	}
+}
+
 // NewInMemoryEventStore creates a new in-memory event store.
 // This implementation is primarily intended for testing and development.
 // For production use, consider a persistent store implementation.
-func NewInMemoryEventStore() Store {
-	return &InMemoryEventStore{
-		data: make(map[Entity][]DomainEvent),
+func NewInMemoryEventStore(opts ...InMemoryStoreOption) Store {
+	store := &InMemoryEventStore{
+		clock: systemClock{},
 	}
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store
 }
 
+// inMemoryShardCount is the number of lock stripes in InMemoryEventStore.
+const inMemoryShardCount = 64
+
+// inMemoryShardSeed is shared by every store so the zero value of
+// InMemoryEventStore is usable.
+var inMemoryShardSeed = maphash.MakeSeed()
+
 // InMemoryEventStore provides an in-memory implementation of the Store interface.
-// It uses a mutex-protected map to store events keyed by entity.
+// Streams are spread across lock stripes by entity, so writers to different
+// aggregates rarely contend, and appends grow each stream in place.
 // This implementation is thread-safe but data is not persisted across restarts.
 type InMemoryEventStore struct {
-	mu   sync.RWMutex
-	data map[Entity][]DomainEvent
+	shards [inMemoryShardCount]memoryShard
+	clock  Clock
+	codec  EventCodec
+}
+
+// memoryShard is one lock stripe of InMemoryEventStore.
+type memoryShard struct {
+	mu      sync.RWMutex
+	streams map[Entity]*memoryStream
+}
+
+// shard returns the lock stripe that owns the entity's stream.
+func (s *InMemoryEventStore) shard(entity Entity) *memoryShard {
+	return &s.shards[maphash.Comparable(inMemoryShardSeed, entity)%inMemoryShardCount]
+}
+
+// stream returns the entity's stream, creating it when create is set.
+// The caller must hold the shard lock, exclusively when create is set.
+func (m *memoryShard) stream(entity Entity, create bool) *memoryStream {
+	stream := m.streams[entity]
+	if stream == nil && create {
+		if m.streams == nil {
+			m.streams = make(map[Entity]*memoryStream)
+		}
+		stream = &memoryStream{}
+		m.streams[entity] = stream
+	}
+	return stream
+}
+
+// memoryStream is the state of one entity's stream in InMemoryEventStore.
+type memoryStream struct {
+	// events holds the retained events, ordered by sequence.
+	events []DomainEvent
+	// head is the number of events ever appended. It survives truncation so
+	// optimistic concurrency keeps working on truncated streams.
+	head          uint64
+	deleted       bool
+	createdAt     int64
+	lastTimestamp int64
+	settings      StreamSettings
+}
+
+// visible returns the retained events that are still within the stream's MaxAge.
+// Timestamps come from the writers' clocks and need not increase with sequence, so
+// the expired prefix is found by scanning from the front: an event stays visible
+// while any earlier one does. The head event is always visible, so a live stream
+// never reads as empty.
+func (m *memoryStream) visible(now int64) []DomainEvent {
+	if m.settings.MaxAge <= 0 {
+		return m.events
+	}
+	cutoff := now - m.settings.MaxAge
+	start := 0
+	for start < len(m.events)-1 && m.events[start].GetTimestamp() < cutoff {
+		start++
+	}
+	return m.events[start:]
+}
+
+// applyRetention drops events outside MaxCount and MaxAge, keeping the head event.
+func (m *memoryStream) applyRetention(now int64) {
+	events := m.visible(now)
+	if limit := m.settings.MaxCount; limit > 0 && uint64(len(events)) > limit {
+		events = events[uint64(len(events))-limit:]
+	}
+	m.dropBefore(len(m.events) - len(events))
+}
+
+// dropBefore removes the first n events. The backing array is reused, and the
+// dropped slots are cleared so their events can be collected; the next append
+// that outgrows the array compacts it.
+func (m *memoryStream) dropBefore(n int) {
+	if n <= 0 {
+		return
+	}
+	clear(m.events[:n])
+	m.events = m.events[n:]
+}
+
+func (s *InMemoryEventStore) now() int64 {
+	if s.clock == nil {
+		return systemClock{}.GetTimestamp()
+	}
+	return s.clock.GetTimestamp()
 }
 
 // LoadEvents implements Store.LoadEvents.
 // It retrieves all events for the specified entity starting from the given `sequence` number.
 func (s *InMemoryEventStore) LoadEvents(ctx context.Context, entity Entity, sequence uint64) ([]DomainEvent, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return s.ReadStream(ctx, entity, ReadOptions{From: sequence})
+}
+
+// ReadStream implements StreamReader.ReadStream.
+// Range bounds are located by binary search, so reads cost the size of the
+// result rather than the size of the stream.
+func (s *InMemoryEventStore) ReadStream(ctx context.Context, entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	events, err := s.readRange(entity, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decodeAll(events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
 
-	events, ok := s.data[entity]
-	if !ok {
+func (s *InMemoryEventStore) readRange(entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	shard := s.shard(entity)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	stream := shard.stream(entity, false)
+	if stream == nil {
 		return []DomainEvent{}, nil
 	}
+	if stream.deleted {
+		return nil, streamDeletedError(entity)
+	}
+	return applyReadOptions(stream.visible(s.now()), opts), nil
+}
 
-	result := make([]DomainEvent, 0, len(events))
-	for _, event := range events {
-		if event.GetSequence() >= sequence {
-			result = append(result, event)
+// LoadEventStream implements StreamingStore.LoadEventStream.
+// Events are copied out in pages of bounded size, so the read lock is never held
+// while the consumer runs and iteration can stop early without scanning the stream.
+func (s *InMemoryEventStore) LoadEventStream(ctx context.Context, entity Entity, sequence uint64) iter.Seq2[DomainEvent, error] {
+	return func(yield func(DomainEvent, error) bool) {
+		page := make([]DomainEvent, 0, inMemoryStreamPageSize)
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			var err error
+			page, err = s.readPage(entity, sequence, page[:0])
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if len(page) == 0 {
+				return
+			}
+			if err := s.decodeAll(page); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			sequence = page[len(page)-1].GetSequence() + 1
+			for _, event := range page {
+				if !yield(event, nil) {
+					return
+				}
+			}
 		}
 	}
-	return result, nil
+}
+
+// readPage appends up to one page of the entity's events, starting at the first
+// event at or after sequence. Pages resume by sequence rather than position, so
+// changes to the stream between pages cannot make the next page skip events.
+func (s *InMemoryEventStore) readPage(entity Entity, sequence uint64, page []DomainEvent) ([]DomainEvent, error) {
+	shard := s.shard(entity)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	stream := shard.stream(entity, false)
+	if stream == nil {
+		return page, nil
+	}
+	if stream.deleted {
+		return page, streamDeletedError(entity)
+	}
+
+	events := stream.visible(s.now())
+	start := searchSequence(events, sequence)
+	end := min(start+cap(page), len(events))
+	return append(page, events[start:end]...), nil
 }
 
 // SaveEvents implements Store.SaveEvents.
 // It appends new events to the entity's event stream with optimistic concurrency control.
 func (s *InMemoryEventStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.appendEvents(entity, events, expectedSequence, false)
+}
+
+// CopyEvents implements StreamCopier.CopyEvents.
+func (s *InMemoryEventStore) CopyEvents(ctx context.Context, entity Entity, events []DomainEvent, startSequence uint64) error {
+	return s.appendEvents(entity, events, startSequence, true)
+}
 
-	if s.data == nil {
-		s.data = make(map[Entity][]DomainEvent)
+// appendEvents adds events after expectedSequence. When copying, a missing stream is
+// created with expectedSequence as its head.
+func (s *InMemoryEventStore) appendEvents(entity Entity, events []DomainEvent, expectedSequence uint64, copying bool) error {
+	events, err := s.encodeAll(events)
+	if err != nil {
+		return err
 	}
 
-	existing := s.data[entity]
-	currentSequence := uint64(len(existing))
+	shard := s.shard(entity)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	stream := shard.streams[entity]
+	if stream != nil && stream.deleted {
+		return streamDeletedError(entity)
+	}
+
+	var currentSequence uint64
+	if stream != nil {
+		currentSequence = stream.head
+	}
+	if currentSequence == 0 && copying {
+		currentSequence = expectedSequence
+	}
 	if expectedSequence != currentSequence {
 		return concurrencyError{expectedSequence: expectedSequence, currentSequence: currentSequence}
 	}
 
-	newEvents := make([]DomainEvent, 0, len(existing)+len(events))
-	newEvents = append(newEvents, existing...)
-	newEvents = append(newEvents, events...)
-	s.data[entity] = newEvents
+	stream = shard.stream(entity, true)
+	now := s.now()
+	stream.events = append(stream.events, events...)
+	stream.head = expectedSequence + uint64(len(events))
+	if stream.createdAt == 0 {
+		stream.createdAt = now
+	}
+	stream.lastTimestamp = now
+	stream.applyRetention(now)
+	return nil
+}
+
+// SoftDeleteStream implements StreamDeleter.SoftDeleteStream.
+func (s *InMemoryEventStore) SoftDeleteStream(ctx context.Context, entity Entity) error {
+	shard := s.shard(entity)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	stream := shard.stream(entity, true)
+	stream.events = nil
+	stream.deleted = true
+	return nil
+}
+
+// HardDeleteStream implements StreamDeleter.HardDeleteStream.
+func (s *InMemoryEventStore) HardDeleteStream(ctx context.Context, entity Entity) error {
+	shard := s.shard(entity)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	delete(shard.streams, entity)
+	return nil
+}
+
+// TruncateStreamBefore implements StreamDeleter.TruncateStreamBefore.
+// The head event is always retained, so a sequence past the head keeps only that
+// event and aggregates loaded afterwards still know the stream's position.
+func (s *InMemoryEventStore) TruncateStreamBefore(ctx context.Context, entity Entity, sequence uint64) error {
+	shard := s.shard(entity)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	stream := shard.stream(entity, false)
+	if stream == nil {
+		return nil
+	}
+	if stream.deleted {
+		return streamDeletedError(entity)
+	}
+
+	if len(stream.events) > 0 {
+		stream.dropBefore(min(searchSequence(stream.events, sequence), len(stream.events)-1))
+	}
+	return nil
+}
+
+// StreamVersion implements StreamVersioner.StreamVersion.
+func (s *InMemoryEventStore) StreamVersion(ctx context.Context, entity Entity) (uint64, bool, error) {
+	shard := s.shard(entity)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	stream := shard.stream(entity, false)
+	if stream == nil {
+		return 0, false, nil
+	}
+	if stream.deleted {
+		return 0, false, streamDeletedError(entity)
+	}
+	return stream.head, stream.head > 0, nil
+}
+
+// GetStreamMetadata implements StreamMetadataStore.GetStreamMetadata.
+func (s *InMemoryEventStore) GetStreamMetadata(ctx context.Context, entity Entity) (StreamMetadata, bool, error) {
+	shard := s.shard(entity)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	stream := shard.stream(entity, false)
+	if stream == nil {
+		return StreamMetadata{}, false, nil
+	}
+	if stream.deleted {
+		return StreamMetadata{}, false, streamDeletedError(entity)
+	}
+
+	return StreamMetadata{
+		CreatedAt:     stream.createdAt,
+		LastSequence:  stream.head,
+		LastTimestamp: stream.lastTimestamp,
+		Settings:      stream.settings.clone(),
+	}, true, nil
+}
+
+// SetStreamSettings implements StreamMetadataStore.SetStreamSettings.
+// Retention settings take effect immediately.
+func (s *InMemoryEventStore) SetStreamSettings(ctx context.Context, entity Entity, settings StreamSettings) error {
+	shard := s.shard(entity)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	stream := shard.stream(entity, true)
+	if stream.deleted {
+		return streamDeletedError(entity)
+	}
+
+	stream.settings = settings.clone()
+	stream.applyRetention(s.now())
+	return nil
+}
+
+// ListStreams implements StreamLister.ListStreams.
+// Streams are ordered by area, tenant, and ID, and each page token records the
+// last entity returned, so pages stay consistent while streams are added.
+func (s *InMemoryEventStore) ListStreams(ctx context.Context, filter StreamFilter) (StreamPage, error) {
+	var after *Entity
+	if filter.PageToken != "" {
+		last, err := decodeStreamPageToken(filter.PageToken)
+		if err != nil {
+			return StreamPage{}, err
+		}
+		after = &last
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultStreamPageSize
+	}
+
+	var entities []Entity
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		for entity, stream := range shard.streams {
+			listed := stream.head > 0
+			if stream.deleted {
+				listed = filter.IncludeDeleted
+			}
+			if !listed || !filter.Matches(entity) {
+				continue
+			}
+			if after != nil && compareEntities(entity, *after) <= 0 {
+				continue
+			}
+			entities = append(entities, entity)
+		}
+		shard.mu.RUnlock()
+	}
+	slices.SortFunc(entities, compareEntities)
+
+	if len(entities) <= limit {
+		return StreamPage{Entities: entities}, nil
+	}
+	entities = entities[:limit:limit]
+	token, err := encodeStreamPageToken(entities[limit-1])
+	if err != nil {
+		return StreamPage{}, err
+	}
+	return StreamPage{Entities: entities, NextPageToken: token}, nil
+}
+
+// encodedEvent is an event held as codec bytes by an InMemoryEventStore with
+// WithStoreCodec. Its metadata stays decoded for sequence and retention lookups.
+type encodedEvent struct {
+	DomainEventBase
+	discriminator string
+	data          []byte
+}
+
+func (e *encodedEvent) GetDiscriminator() string { return e.discriminator }
+func (e *encodedEvent) GetSpaces() []string      { return []string{e.Metadata.Entity.Area} }
+
+// encodeAll returns events as encodedEvents when the store has a codec.
+func (s *InMemoryEventStore) encodeAll(events []DomainEvent) ([]DomainEvent, error) {
+	if s.codec == nil {
+		return events, nil
+	}
+
+	encoded := make([]DomainEvent, 0, len(events))
+	for _, event := range events {
+		data, err := s.codec.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, &encodedEvent{
+			DomainEventBase: DomainEventBase{Metadata: event.GetMetadata()},
+			discriminator:   event.GetDiscriminator(),
+			data:            data,
+		})
+	}
+	return encoded, nil
+}
+
+// decodeAll replaces encodedEvents in a slice the caller owns with decoded events.
+func (s *InMemoryEventStore) decodeAll(events []DomainEvent) error {
+	for i, event := range events {
+		encoded, ok := event.(*encodedEvent)
+		if !ok {
+			continue
+		}
+		decoded, err := s.codec.Unmarshal(encoded.data)
+		if err != nil {
+			return err
+		}
+		events[i] = decoded
+	}
 	return nil
 }
 
@@ -78,3 +498,7 @@ func (e concurrencyError) Error() string {
 func (e concurrencyError) Unwrap() error {
 	return ErrConcurrency
 }
+
+func streamDeletedError(entity Entity) error {
+	return wrapSentinelError(fmt.Sprintf("stream %s/%s has been deleted", entity.Area, entity.ID), ErrStreamDeleted)
+}
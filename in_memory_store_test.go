@@ -3,8 +3,11 @@ package es
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -101,6 +104,26 @@ func TestShouldReturnConcurrencyErrorWhenCreatingMissingStreamWithUnexpectedSequ
 	assert.EqualError(t, err, "version mismatch: expected 1, got 0")
 }
 
+func TestShouldCreateMissingStreamAtStartSequenceWhenCopying(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 5)
+	require.ErrorIs(t, store.SaveEvents(ctx, entity, events[3:4], 3), ErrConcurrency)
+
+	// Act
+	err := store.CopyEvents(ctx, entity, events[3:4], 3)
+
+	// Assert
+	require.NoError(t, err)
+	assert.ErrorIs(t, store.CopyEvents(ctx, entity, events[4:], 3), ErrConcurrency)
+	assert.NoError(t, store.SaveEvents(ctx, entity, events[4:], 4))
+	version, _, versionErr := StreamVersion(ctx, store, entity)
+	require.NoError(t, versionErr)
+	assert.Equal(t, uint64(5), version)
+}
+
 func TestShouldReturnConcurrencyErrorWhenConcurrentWritersAppendToSameStream(t *testing.T) {
 	// Arrange
 	store := NewInMemoryEventStore()
@@ -162,3 +185,498 @@ func TestShouldReturnConcurrencyErrorWhenConcurrentWritersAppendToSameStream(t *
 	require.NoError(t, err)
 	assert.Len(t, loadedEvents, 2)
 }
+
+func TestShouldStreamEventsAcrossPagesInOrder(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, inMemoryStreamPageSize*2+3)
+	require.NoError(t, store.SaveEvents(ctx, entity, events, 0))
+
+	// Act
+	var sequences []uint64
+	for event, err := range store.LoadEventStream(ctx, entity, 3) {
+		require.NoError(t, err)
+		sequences = append(sequences, event.GetSequence())
+	}
+
+	// Assert
+	assert.Len(t, sequences, len(events)-2)
+	assert.Equal(t, uint64(3), sequences[0])
+	assert.Equal(t, uint64(len(events)), sequences[len(sequences)-1])
+}
+
+func TestShouldNotSkipEventsWhenStreamIsTruncatedBetweenPages(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, inMemoryStreamPageSize*2)
+	require.NoError(t, store.SaveEvents(ctx, entity, events, 0))
+
+	// Act
+	var sequences []uint64
+	for event, err := range store.LoadEventStream(ctx, entity, 0) {
+		require.NoError(t, err)
+		sequences = append(sequences, event.GetSequence())
+		if len(sequences) == 1 {
+			require.NoError(t, store.TruncateStreamBefore(ctx, entity, 100))
+		}
+	}
+
+	// Assert
+	require.Len(t, sequences, len(events))
+	for i, sequence := range sequences {
+		assert.Equal(t, uint64(i+1), sequence)
+	}
+}
+
+func TestShouldStopStreamingWhenConsumerBreaks(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 10), 0))
+
+	// Act
+	read := 0
+	for _, err := range store.LoadEventStream(ctx, entity, 0) {
+		require.NoError(t, err)
+		read++
+		if read == 2 {
+			break
+		}
+	}
+
+	// Assert
+	assert.Equal(t, 2, read)
+}
+
+func TestShouldYieldContextErrorWhenStreamingCancelled(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx, cancel := context.WithCancel(context.Background())
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0))
+	cancel()
+
+	// Act
+	var errs []error
+	for event, err := range store.LoadEventStream(ctx, entity, 0) {
+		assert.Nil(t, event)
+		errs = append(errs, err)
+	}
+
+	// Assert
+	assert.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+}
+
+func newDummyEvents(entity Entity, count int) []DomainEvent {
+	events := make([]DomainEvent, 0, count)
+	for i := range count {
+		event := &DummyCreated{Name: fmt.Sprintf("name-%d", i+1)}
+		event.SetMetadata(EventMetadata{
+			Entity:   entity,
+			EventID:  uuid.New(),
+			Sequence: uint64(i) + 1,
+		})
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestShouldReadBoundedRangeFromInMemoryStore(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 10), 0))
+
+	// Act
+	events, err := store.ReadStream(ctx, entity, ReadOptions{From: 3, To: 8, Limit: 4})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 4, 5, 6}, sequencesOf(events))
+}
+
+func TestShouldReadLastEventsBackwardsFromInMemoryStore(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 10), 0))
+
+	// Act
+	events, err := store.ReadStream(ctx, entity, ReadOptions{Limit: 3, Backwards: true})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{10, 9, 8}, sequencesOf(events))
+}
+
+func TestShouldReturnEmptyRangeWhenFromIsAfterTo(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 5), 0))
+
+	// Act
+	events, err := store.ReadStream(ctx, entity, ReadOptions{From: 4, To: 2})
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotNil(t, events)
+	assert.Empty(t, events)
+}
+
+func sequencesOf(events []DomainEvent) []uint64 {
+	sequences := make([]uint64, 0, len(events))
+	for _, event := range events {
+		sequences = append(sequences, event.GetSequence())
+	}
+	return sequences
+}
+
+func TestShouldRejectReadsAndAppendsAfterSoftDelete(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 2), 0))
+
+	// Act
+	err := store.SoftDeleteStream(ctx, entity)
+
+	// Assert
+	require.NoError(t, err)
+	_, loadErr := store.LoadEvents(ctx, entity, 0)
+	assert.ErrorIs(t, loadErr, ErrStreamDeleted)
+	saveErr := store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 2)
+	assert.ErrorIs(t, saveErr, ErrStreamDeleted)
+	for _, streamErr := range store.LoadEventStream(ctx, entity, 0) {
+		assert.ErrorIs(t, streamErr, ErrStreamDeleted)
+	}
+}
+
+func TestShouldAllowRecreatingStreamAfterHardDelete(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 2), 0))
+	require.NoError(t, store.SoftDeleteStream(ctx, entity))
+
+	// Act
+	err := store.HardDeleteStream(ctx, entity)
+
+	// Assert
+	require.NoError(t, err)
+	events, loadErr := store.LoadEvents(ctx, entity, 0)
+	assert.NoError(t, loadErr)
+	assert.Empty(t, events)
+	assert.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0))
+}
+
+func TestShouldKeepHeadSequenceWhenTruncatingStream(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 6)
+	require.NoError(t, store.SaveEvents(ctx, entity, events[:5], 0))
+
+	// Act
+	err := store.TruncateStreamBefore(ctx, entity, 4)
+
+	// Assert
+	require.NoError(t, err)
+	loaded, loadErr := store.LoadEvents(ctx, entity, 0)
+	require.NoError(t, loadErr)
+	assert.Equal(t, []uint64{4, 5}, sequencesOf(loaded))
+	assert.ErrorIs(t, store.SaveEvents(ctx, entity, events[5:], 2), ErrConcurrency)
+	assert.NoError(t, store.SaveEvents(ctx, entity, events[5:], 5))
+}
+
+func TestShouldKeepHeadEventWhenTruncatingPastHead(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	repository := NewRepository(store)
+	dummy := newDummyWithID(uuid.New())
+	require.NoError(t, dummy.Create("first"))
+	require.NoError(t, dummy.Create("second"))
+	require.NoError(t, repository.Save(ctx, dummy))
+
+	// Act
+	err := store.(*InMemoryEventStore).TruncateStreamBefore(ctx, dummy.GetEntity(), 10)
+
+	// Assert
+	require.NoError(t, err)
+	loaded := newDummyWithID(dummy.GetAggregateID())
+	require.NoError(t, repository.Load(ctx, loaded))
+	assert.Equal(t, "second", loaded.name)
+	assert.Equal(t, uint64(2), loaded.GetCommittedSequence())
+	require.NoError(t, loaded.Create("third"))
+	assert.NoError(t, repository.Save(ctx, loaded))
+}
+
+func TestShouldTrackStreamMetadataOnAppend(t *testing.T) {
+	// Arrange
+	clock := NewFakeClock(100)
+	store := NewInMemoryEventStore(WithStoreClock(clock)).(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 3)
+	require.NoError(t, store.SaveEvents(ctx, entity, events[:1], 0))
+	clock.Set(250)
+
+	// Act
+	require.NoError(t, store.SaveEvents(ctx, entity, events[1:], 1))
+	metadata, found, err := store.GetStreamMetadata(ctx, entity)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.True(t, metadata.Exists())
+	assert.Equal(t, int64(100), metadata.CreatedAt)
+	assert.Equal(t, int64(250), metadata.LastTimestamp)
+	assert.Equal(t, uint64(3), metadata.LastSequence)
+}
+
+func TestShouldStoreSettingsBeforeFirstAppend(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	settings := StreamSettings{Custom: map[string]string{"owner": "billing"}, ACL: []string{"ops"}}
+
+	// Act
+	err := store.SetStreamSettings(ctx, entity, settings)
+	settings.Custom["owner"] = "mutated"
+
+	// Assert
+	require.NoError(t, err)
+	metadata, found, getErr := store.GetStreamMetadata(ctx, entity)
+	require.NoError(t, getErr)
+	assert.True(t, found)
+	assert.False(t, metadata.Exists())
+	assert.Equal(t, "billing", metadata.Settings.Custom["owner"])
+	assert.Equal(t, []string{"ops"}, metadata.Settings.ACL)
+}
+
+func TestShouldApplyMaxCountRetention(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 5)
+	require.NoError(t, store.SetStreamSettings(ctx, entity, StreamSettings{MaxCount: 2}))
+
+	// Act
+	require.NoError(t, store.SaveEvents(ctx, entity, events[:4], 0))
+	require.NoError(t, store.SaveEvents(ctx, entity, events[4:], 4))
+
+	// Assert
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4, 5}, sequencesOf(loaded))
+}
+
+func TestShouldHideEventsOlderThanMaxAge(t *testing.T) {
+	// Arrange
+	clock := NewFakeClock(1000)
+	store := NewInMemoryEventStore(WithStoreClock(clock)).(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 3)
+	for i, event := range events {
+		event.(*DummyCreated).Metadata.Timestamp = int64(1000 + i*100)
+	}
+	require.NoError(t, store.SaveEvents(ctx, entity, events, 0))
+	require.NoError(t, store.SetStreamSettings(ctx, entity, StreamSettings{MaxAge: 150}))
+
+	// Act
+	clock.Set(1300)
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3}, sequencesOf(loaded))
+}
+
+func TestShouldHideOnlyExpiredPrefixWhenTimestampsAreOutOfOrder(t *testing.T) {
+	// Arrange
+	clock := NewFakeClock(1000)
+	store := NewInMemoryEventStore(WithStoreClock(clock)).(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 4)
+	for i, timestamp := range []int64{1000, 1300, 1000, 1300} {
+		events[i].(*DummyCreated).Metadata.Timestamp = timestamp
+	}
+	require.NoError(t, store.SaveEvents(ctx, entity, events, 0))
+	require.NoError(t, store.SetStreamSettings(ctx, entity, StreamSettings{MaxAge: 150}))
+
+	// Act
+	clock.Set(1300)
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3, 4}, sequencesOf(loaded))
+}
+
+func TestShouldKeepHeadEventWhenMaxAgeExpiresStream(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	clock := NewFakeClock(1000)
+	store := NewInMemoryEventStore(WithStoreClock(clock))
+	repository := NewRepository(store)
+	dummy := newDummyWithID(uuid.New())
+	require.NoError(t, dummy.Create("first"))
+	require.NoError(t, dummy.Create("second"))
+	for _, event := range dummy.GetUncommittedEvents() {
+		event.(*DummyCreated).Metadata.Timestamp = 1000
+	}
+	require.NoError(t, repository.Save(ctx, dummy))
+	require.NoError(t, store.(*InMemoryEventStore).SetStreamSettings(ctx, dummy.GetEntity(), StreamSettings{MaxAge: 100}))
+
+	// Act
+	clock.Set(5000)
+	loaded := newDummyWithID(dummy.GetAggregateID())
+	err := repository.Load(ctx, loaded)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "second", loaded.name)
+	assert.Equal(t, uint64(2), loaded.GetCommittedSequence())
+	require.NoError(t, loaded.Create("third"))
+	require.NoError(t, repository.Save(ctx, loaded))
+	events, loadErr := store.LoadEvents(ctx, dummy.GetEntity(), 0)
+	require.NoError(t, loadErr)
+	assert.Equal(t, []uint64{3}, sequencesOf(events))
+}
+
+func TestShouldReturnStreamVersionWithoutReadingEvents(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 4), 0))
+	require.NoError(t, store.TruncateStreamBefore(ctx, entity, 5))
+
+	// Act
+	version, exists, err := store.StreamVersion(ctx, entity)
+	missingVersion, missingExists, missingErr := store.StreamVersion(ctx, NewEntity(uuid.New(), AreaDummy))
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, missingErr)
+	assert.True(t, exists)
+	assert.Equal(t, uint64(4), version)
+	assert.False(t, missingExists)
+	assert.Zero(t, missingVersion)
+}
+
+func TestShouldKeepStreamsConsistentUnderParallelWriters(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore()
+	ctx := context.Background()
+	entities := make([]Entity, 32)
+	for i := range entities {
+		entities[i] = NewEntity(uuid.New(), AreaDummy)
+	}
+	const appendsPerEntity = 50
+
+	// Act
+	var wg sync.WaitGroup
+	for _, entity := range entities {
+		wg.Go(func() {
+			for i, event := range newDummyEvents(entity, appendsPerEntity) {
+				assert.NoError(t, store.SaveEvents(ctx, entity, []DomainEvent{event}, uint64(i)))
+			}
+		})
+	}
+	wg.Wait()
+
+	// Assert
+	for _, entity := range entities {
+		loaded, err := store.LoadEvents(ctx, entity, 0)
+		require.NoError(t, err)
+		assert.Len(t, loaded, appendsPerEntity)
+		assert.Equal(t, uint64(appendsPerEntity), loaded[len(loaded)-1].GetSequence())
+	}
+}
+
+func BenchmarkInMemoryEventStoreParallelWriters(b *testing.B) {
+	store := NewInMemoryEventStore()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		entity := NewEntity(uuid.New(), AreaDummy)
+		var sequence uint64
+		for pb.Next() {
+			event := newDummyEvents(entity, 1)[0]
+			if err := store.SaveEvents(ctx, entity, []DomainEvent{event}, sequence); err != nil {
+				b.Fatal(err)
+			}
+			sequence++
+		}
+	})
+}
+
+func BenchmarkInMemoryEventStoreParallelWritersManyAggregates(b *testing.B) {
+	store := NewInMemoryEventStore()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			entity := NewEntity(uuid.New(), AreaDummy)
+			if err := store.SaveEvents(ctx, entity, newDummyEvents(entity, 2), 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkInMemoryEventStoreParallelReadersAndWriters(b *testing.B) {
+	store := NewInMemoryEventStore()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		entity := NewEntity(uuid.New(), AreaDummy)
+		var sequence uint64
+		for pb.Next() {
+			if sequence%4 == 0 {
+				event := newDummyEvents(entity, 1)[0]
+				if err := store.SaveEvents(ctx, entity, []DomainEvent{event}, sequence/4); err != nil {
+					b.Fatal(err)
+				}
+			} else if _, err := store.LoadEvents(ctx, entity, sequence/4); err != nil {
+				b.Fatal(err)
+			}
+			sequence++
+		}
+	})
+}
+
+func BenchmarkInMemoryEventStoreAppendToLongStream(b *testing.B) {
+	store := NewInMemoryEventStore()
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 1)
+
+	b.ReportAllocs()
+	for i := range b.N {
+		if err := store.SaveEvents(ctx, entity, events, uint64(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package es
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MigrationTransform rewrites an event on its way from the source store to the
+// target store. Changing Metadata.Entity moves the event to another stream; every
+// event of a source stream must map to the same target stream.
+type MigrationTransform func(ctx context.Context, envelope Envelope) (Envelope, error)
+
+// UpcastTransform applies the registered upcasters, so transforms after it see
+// current event shapes. Decoding for the target upcasts in any case.
+func UpcastTransform() MigrationTransform {
+	return func(ctx context.Context, envelope Envelope) (Envelope, error) {
+		return envelope.Upcast()
+	}
+}
+
+// RenameAreaTransform moves streams in area from to area to.
+func RenameAreaTransform(from, to string) MigrationTransform {
+	return func(ctx context.Context, envelope Envelope) (Envelope, error) {
+		if envelope.Metadata.Entity.Area == from {
+			envelope.Metadata.Entity.Area = to
+		}
+		return envelope, nil
+	}
+}
+
+// RemapTenantTransform moves tenant-scoped streams of tenant from to tenant to.
+func RemapTenantTransform(from, to uuid.UUID) MigrationTransform {
+	return func(ctx context.Context, envelope Envelope) (Envelope, error) {
+		if envelope.Metadata.Entity.Scope == ScopeTenant && envelope.Metadata.Entity.TenantID == from {
+			envelope.Metadata.Entity.TenantID = to
+		}
+		return envelope, nil
+	}
+}
+
+// MigrationCheckpoints records how far each source stream has been copied, so an
+// interrupted migration resumes where it stopped.
+type MigrationCheckpoints interface {
+	// Checkpoint returns the last copied sequence of a source stream.
+	Checkpoint(ctx context.Context, source Entity) (uint64, bool, error)
+	SaveCheckpoint(ctx context.Context, source Entity, sequence uint64) error
+}
+
+// InMemoryMigrationCheckpoints is a MigrationCheckpoints kept in memory.
+// It is safe for concurrent use.
+type InMemoryMigrationCheckpoints struct {
+	mu          sync.Mutex
+	checkpoints map[Entity]uint64
+}
+
+// NewInMemoryMigrationCheckpoints creates empty in-memory checkpoints.
+func NewInMemoryMigrationCheckpoints() *InMemoryMigrationCheckpoints {
+	return &InMemoryMigrationCheckpoints{checkpoints: make(map[Entity]uint64)}
+}
+
+// Checkpoint implements MigrationCheckpoints.
+func (c *InMemoryMigrationCheckpoints) Checkpoint(ctx context.Context, source Entity) (uint64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sequence, ok := c.checkpoints[source]
+	return sequence, ok, nil
+}
+
+// SaveCheckpoint implements MigrationCheckpoints.
+func (c *InMemoryMigrationCheckpoints) SaveCheckpoint(ctx context.Context, source Entity, sequence uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkpoints[source] = sequence
+	return nil
+}
+
+// MigratorOption configures a Migrator.
+type MigratorOption func(*Migrator)
+
+// WithMigrationTransforms adds transforms, applied in order to every event.
+func WithMigrationTransforms(transforms ...MigrationTransform) MigratorOption {
+	return func(m *Migrator) {
+		m.transforms = append(m.transforms, transforms...)
+	}
+}
+
+// WithMigrationCheckpoints records progress after every batch and resumes from it.
+func WithMigrationCheckpoints(checkpoints MigrationCheckpoints) MigratorOption {
+	return func(m *Migrator) {
+		m.checkpoints = checkpoints
+	}
+}
+
+// WithMigrationBatchSize sets how many events are appended per SaveEvents call.
+func WithMigrationBatchSize(size int) MigratorOption {
+	return func(m *Migrator) {
+		if size > 0 {
+			m.batchSize = size
+		}
+	}
+}
+
+// Migrator copies streams from one store to another, verifying each copy.
+type Migrator struct {
+	source      Store
+	target      Store
+	transforms  []MigrationTransform
+	checkpoints MigrationCheckpoints
+	batchSize   int
+}
+
+// NewMigrator creates a migrator from source to target.
+func NewMigrator(source, target Store, opts ...MigratorOption) *Migrator {
+	m := &Migrator{source: source, target: target, batchSize: importBatchSize}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// StreamReport is the outcome of migrating one stream. Checksums are SHA-256
+// digests over the stream's envelopes: the source side after transforms and
+// upcasting, the target side as read back once the copy is complete.
+type StreamReport struct {
+	Source Entity
+	Target Entity
+	// FirstSequence is the sequence of the source stream's first event. It is above
+	// 1 when the source was truncated or trimmed by retention; the target stream
+	// then starts at the same sequence.
+	FirstSequence  uint64
+	Events         int
+	Copied         int
+	SourceChecksum string
+	TargetChecksum string
+	// Deleted reports that the source stream was soft-deleted. Its target stream is
+	// tombstoned instead of copied.
+	Deleted bool
+}
+
+// Verified reports whether the target stream matches the transformed source.
+func (r StreamReport) Verified() bool {
+	return r.SourceChecksum == r.TargetChecksum
+}
+
+// MigrationReport lists the outcome of every migrated stream.
+type MigrationReport struct {
+	Streams []StreamReport
+}
+
+// Mismatched returns the streams whose checksums differ.
+func (r MigrationReport) Mismatched() []StreamReport {
+	var mismatched []StreamReport
+	for _, stream := range r.Streams {
+		if !stream.Verified() {
+			mismatched = append(mismatched, stream)
+		}
+	}
+	return mismatched
+}
+
+// Migrate copies the given streams in order. Events are appended through
+// CopyEvents with their original sequences as expectedSequence checks, so a target
+// stream that already holds other events fails with ErrConcurrency, and a source
+// stream that starts above sequence 1 is recreated at the same position when the
+// target implements StreamCopier. Stream settings are copied when both stores
+// implement StreamMetadataStore, and a soft-deleted source stream is soft-deleted
+// in the target. Events at or below a stream's checkpoint are verified but not
+// copied again. The report covers the streams processed before any error; when
+// every copy succeeds but a checksum differs, the error matches ErrChecksumMismatch.
+func (m *Migrator) Migrate(ctx context.Context, streams []Entity) (MigrationReport, error) {
+	var report MigrationReport
+	for _, source := range streams {
+		stream, err := m.migrateStream(ctx, source)
+		if err != nil {
+			return report, fmt.Errorf("migrate %s/%s: %w", source.Area, source.ID, err)
+		}
+		report.Streams = append(report.Streams, stream)
+	}
+
+	if mismatched := report.Mismatched(); len(mismatched) > 0 {
+		return report, wrapSentinelError(fmt.Sprintf("%d migrated stream(s) do not match their source", len(mismatched)), ErrChecksumMismatch)
+	}
+	return report, nil
+}
+
+// MigrateAll migrates every source stream matching filter, as listed by the
+// source store's StreamLister, with the same checks as Migrate. Soft-deleted
+// streams are always listed, so their tombstones are carried to the target.
+func (m *Migrator) MigrateAll(ctx context.Context, filter StreamFilter) (MigrationReport, error) {
+	filter.IncludeDeleted = true
+	var streams []Entity
+	for entity, err := range Streams(ctx, m.source, filter) {
+		if err != nil {
+			return MigrationReport{}, err
+		}
+		streams = append(streams, entity)
+	}
+	return m.Migrate(ctx, streams)
+}
+
+func (m *Migrator) migrateStream(ctx context.Context, source Entity) (StreamReport, error) {
+	report := StreamReport{Source: source}
+	var checkpoint uint64
+	if m.checkpoints != nil {
+		var err error
+		if checkpoint, _, err = m.checkpoints.Checkpoint(ctx, source); err != nil {
+			return report, err
+		}
+	}
+
+	sourceSum := sha256.New()
+	var batch []DomainEvent
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		last := batch[len(batch)-1].GetSequence()
+		if err := CopyEvents(ctx, m.target, report.Target, batch, batch[0].GetSequence()-1); err != nil {
+			return err
+		}
+		report.Copied += len(batch)
+		batch = nil
+		if m.checkpoints != nil {
+			return m.checkpoints.SaveCheckpoint(ctx, source, last)
+		}
+		return nil
+	}
+
+	for event, err := range StreamEvents(ctx, m.source, source, 0) {
+		if errors.Is(err, ErrStreamDeleted) && report.Events == 0 {
+			report.Deleted = true
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		envelope, err := m.transform(ctx, event)
+		if err != nil {
+			return report, err
+		}
+
+		target := envelope.Metadata.Entity
+		if report.Events == 0 {
+			report.Target = target
+			report.FirstSequence = envelope.Metadata.Sequence
+		} else if target != report.Target {
+			return report, fmt.Errorf("event %s maps to stream %s/%s, want %s/%s",
+				envelope.Metadata.EventID, target.Area, target.ID, report.Target.Area, report.Target.ID)
+		}
+		report.Events++
+
+		// The checksum covers the event as it will be written, after decoding has
+		// applied any upcasters, so it can be compared with the target as read back.
+		decoded, err := envelope.Event()
+		if err != nil {
+			return report, err
+		}
+		if err := writeChecksum(sourceSum, decoded); err != nil {
+			return report, err
+		}
+		if envelope.Metadata.Sequence <= checkpoint {
+			continue
+		}
+		batch = append(batch, decoded)
+		if len(batch) == m.batchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+	report.SourceChecksum = hex.EncodeToString(sourceSum.Sum(nil))
+
+	if report.Events == 0 {
+		target, err := m.targetEntity(ctx, source)
+		if err != nil {
+			return report, err
+		}
+		report.Target = target
+	}
+	if report.Deleted {
+		report.TargetChecksum = report.SourceChecksum
+		return report, m.tombstone(ctx, report.Target)
+	}
+	if err := m.copySettings(ctx, source, report.Target); err != nil {
+		return report, err
+	}
+
+	targetSum := sha256.New()
+	if report.Events > 0 {
+		for event, err := range StreamEvents(ctx, m.target, report.Target, 0) {
+			if err != nil {
+				return report, err
+			}
+			if err := writeChecksum(targetSum, event); err != nil {
+				return report, err
+			}
+		}
+	}
+	report.TargetChecksum = hex.EncodeToString(targetSum.Sum(nil))
+	return report, nil
+}
+
+// targetEntity maps a source stream without readable events to its target stream
+// by running the transforms over an envelope that carries only the entity.
+func (m *Migrator) targetEntity(ctx context.Context, source Entity) (Entity, error) {
+	envelope := Envelope{Metadata: EventMetadata{Entity: source}}
+	for _, transform := range m.transforms {
+		var err error
+		if envelope, err = transform(ctx, envelope); err != nil {
+			return Entity{}, err
+		}
+	}
+	return envelope.Metadata.Entity, nil
+}
+
+// tombstone soft-deletes the target of a soft-deleted source stream.
+func (m *Migrator) tombstone(ctx context.Context, target Entity) error {
+	deleter, ok := m.target.(StreamDeleter)
+	if !ok {
+		return unsupportedError(m.target, "stream deletion")
+	}
+	return deleter.SoftDeleteStream(ctx, target)
+}
+
+// copySettings copies the source stream's settings, if it has any, to the target.
+func (m *Migrator) copySettings(ctx context.Context, source, target Entity) error {
+	sourceStore, ok := m.source.(StreamMetadataStore)
+	if !ok {
+		return nil
+	}
+	metadata, found, err := sourceStore.GetStreamMetadata(ctx, source)
+	if errors.Is(err, ErrUnsupported) {
+		return nil
+	}
+	if err != nil || !found || metadata.Settings.isZero() {
+		return err
+	}
+
+	targetStore, ok := m.target.(StreamMetadataStore)
+	if !ok {
+		return unsupportedError(m.target, "stream metadata")
+	}
+	return targetStore.SetStreamSettings(ctx, target, metadata.Settings)
+}
+
+func (m *Migrator) transform(ctx context.Context, event DomainEvent) (Envelope, error) {
+	envelope, err := NewEnvelope(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+	for _, transform := range m.transforms {
+		if envelope, err = transform(ctx, envelope); err != nil {
+			return Envelope{}, err
+		}
+	}
+	return envelope, nil
+}
+
+// writeChecksum feeds one event, as a JSON envelope line, into a stream checksum.
+func writeChecksum(sum hash.Hash, event DomainEvent) error {
+	envelope, err := NewEnvelope(event)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	sum.Write(append(data, '\n'))
+	return nil
+}
@@ -0,0 +1,203 @@
+package es
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldMigrateStreamsWithVerifiedChecksums(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	target := NewInMemoryEventStore()
+	first := NewEntity(uuid.New(), AreaDummy)
+	second := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, source.SaveEvents(ctx, first, newStampedDummyEvents(first, 5), 0))
+	require.NoError(t, source.SaveEvents(ctx, second, newStampedDummyEvents(second, 1), 0))
+	migrator := NewMigrator(source, target, WithMigrationBatchSize(2))
+
+	// Act
+	report, err := migrator.Migrate(ctx, []Entity{first, second})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, report.Streams, 2)
+	assert.Equal(t, 5, report.Streams[0].Copied)
+	assert.True(t, report.Streams[0].Verified())
+	assert.NotEmpty(t, report.Streams[0].SourceChecksum)
+	sourceEvents, err := source.LoadEvents(ctx, first, 0)
+	require.NoError(t, err)
+	targetEvents, err := target.LoadEvents(ctx, first, 0)
+	require.NoError(t, err)
+	assert.Equal(t, sourceEvents, targetEvents)
+}
+
+func TestShouldRenameAreaAndRemapTenantDuringMigration(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	target := NewInMemoryEventStore()
+	oldTenant, newTenant := uuid.New(), uuid.New()
+	entity := NewTenantEntity(oldTenant, uuid.New(), AreaTest)
+	require.NoError(t, source.SaveEvents(ctx, entity, newStampedDummyEvents(entity, 2), 0))
+	migrator := NewMigrator(source, target, WithMigrationTransforms(
+		RenameAreaTransform(AreaTest, AreaDummy),
+		RemapTenantTransform(oldTenant, newTenant),
+	))
+
+	// Act
+	report, err := migrator.Migrate(ctx, []Entity{entity})
+
+	// Assert
+	require.NoError(t, err)
+	moved := NewTenantEntity(newTenant, entity.ID, AreaDummy)
+	assert.Equal(t, moved, report.Streams[0].Target)
+	events, err := target.LoadEvents(ctx, moved, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, moved, events[1].GetEntity())
+	assert.Equal(t, uint64(2), events[1].GetSequence())
+}
+
+func TestShouldResumeMigrationFromCheckpoint(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	target := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newStampedDummyEvents(entity, 4)
+	require.NoError(t, source.SaveEvents(ctx, entity, events, 0))
+	require.NoError(t, target.SaveEvents(ctx, entity, events[:3], 0))
+	checkpoints := NewInMemoryMigrationCheckpoints()
+	require.NoError(t, checkpoints.SaveCheckpoint(ctx, entity, 3))
+	migrator := NewMigrator(source, target, WithMigrationCheckpoints(checkpoints))
+
+	// Act
+	report, err := migrator.Migrate(ctx, []Entity{entity})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Streams[0].Events)
+	assert.Equal(t, 1, report.Streams[0].Copied)
+	assert.True(t, report.Streams[0].Verified())
+	checkpoint, ok, err := checkpoints.Checkpoint(ctx, entity)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(4), checkpoint)
+}
+
+func TestShouldReportChecksumMismatch(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	target := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newStampedDummyEvents(entity, 2)
+	require.NoError(t, source.SaveEvents(ctx, entity, events, 0))
+	tampered := newStampedDummyEvents(entity, 1)
+	tampered[0].(*DummyCreated).Name = "tampered"
+	require.NoError(t, target.SaveEvents(ctx, entity, tampered, 0))
+	checkpoints := NewInMemoryMigrationCheckpoints()
+	require.NoError(t, checkpoints.SaveCheckpoint(ctx, entity, 1))
+	migrator := NewMigrator(source, target, WithMigrationCheckpoints(checkpoints))
+
+	// Act
+	report, err := migrator.Migrate(ctx, []Entity{entity})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	require.Len(t, report.Mismatched(), 1)
+	assert.Equal(t, entity, report.Mismatched()[0].Source)
+}
+
+func TestShouldRejectMigrationIntoOccupiedStream(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	target := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, source.SaveEvents(ctx, entity, newStampedDummyEvents(entity, 2), 0))
+	require.NoError(t, target.SaveEvents(ctx, entity, newStampedDummyEvents(entity, 1), 0))
+
+	// Act
+	_, err := NewMigrator(source, target).Migrate(ctx, []Entity{entity})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrConcurrency)
+}
+
+func TestShouldMigrateTruncatedStreamIntoEmptyTarget(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	target := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newStampedDummyEvents(entity, 6)
+	require.NoError(t, source.SaveEvents(ctx, entity, events[:5], 0))
+	require.NoError(t, source.(*InMemoryEventStore).TruncateStreamBefore(ctx, entity, 4))
+
+	// Act
+	report, err := NewMigrator(source, target).Migrate(ctx, []Entity{entity})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, report.Streams, 1)
+	assert.Equal(t, uint64(4), report.Streams[0].FirstSequence)
+	assert.Equal(t, 2, report.Streams[0].Copied)
+	assert.True(t, report.Streams[0].Verified())
+	loaded, err := target.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4, 5}, sequencesOf(loaded))
+	assert.NoError(t, target.SaveEvents(ctx, entity, events[5:], 5))
+}
+
+func TestShouldCopyStreamSettingsDuringMigration(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	target := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	settings := StreamSettings{MaxCount: 10, ACL: []string{"ops"}, Custom: map[string]string{"owner": "billing"}}
+	require.NoError(t, source.SaveEvents(ctx, entity, newStampedDummyEvents(entity, 2), 0))
+	require.NoError(t, source.(StreamMetadataStore).SetStreamSettings(ctx, entity, settings))
+
+	// Act
+	_, err := NewMigrator(source, target).Migrate(ctx, []Entity{entity})
+
+	// Assert
+	require.NoError(t, err)
+	metadata, _, err := target.(StreamMetadataStore).GetStreamMetadata(ctx, entity)
+	require.NoError(t, err)
+	assert.Equal(t, settings, metadata.Settings)
+}
+
+func TestShouldCarryTombstonesWhenMigratingAllStreams(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	target := NewInMemoryEventStore()
+	live := NewEntity(uuid.New(), AreaDummy)
+	deleted := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, source.SaveEvents(ctx, live, newStampedDummyEvents(live, 2), 0))
+	require.NoError(t, source.SaveEvents(ctx, deleted, newStampedDummyEvents(deleted, 2), 0))
+	require.NoError(t, source.(StreamDeleter).SoftDeleteStream(ctx, deleted))
+
+	// Act
+	report, err := NewMigrator(source, target, WithMigrationTransforms(RenameAreaTransform(AreaDummy, "renamed"))).
+		MigrateAll(ctx, StreamFilter{})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, report.Streams, 2)
+	moved := deleted
+	moved.Area = "renamed"
+	for _, stream := range report.Streams {
+		assert.Equal(t, stream.Source == deleted, stream.Deleted)
+	}
+	err = target.SaveEvents(ctx, moved, newStampedDummyEvents(moved, 1), 0)
+	assert.ErrorIs(t, err, ErrStreamDeleted)
+}
@@ -0,0 +1,316 @@
+package es
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// errMsgpackTruncated reports MessagePack input that ends inside a value.
+var errMsgpackTruncated = errors.New("msgpack: unexpected end of input")
+
+// msgpackWriter appends MessagePack encodings of the generic values produced by
+// decoding JSON: nil, bool, int64, uint64, float64, string, []byte, []any, and
+// map[string]any. Map keys are written in sorted order so output is deterministic.
+type msgpackWriter struct {
+	buf []byte
+}
+
+func (w *msgpackWriter) writeValue(value any) error {
+	switch v := value.(type) {
+	case nil:
+		w.buf = append(w.buf, 0xc0)
+	case bool:
+		if v {
+			w.buf = append(w.buf, 0xc3)
+		} else {
+			w.buf = append(w.buf, 0xc2)
+		}
+	case int64:
+		w.writeInt(v)
+	case uint64:
+		w.writeUint(v)
+	case float64:
+		w.buf = append(w.buf, 0xcb)
+		w.buf = binary.BigEndian.AppendUint64(w.buf, math.Float64bits(v))
+	case string:
+		w.writeString(v)
+	case []byte:
+		w.writeBinary(v)
+	case []any:
+		w.writeHeader(len(v), 0x90, 16, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := w.writeValue(item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		w.writeHeader(len(v), 0x80, 16, 0xde, 0xdf)
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			w.writeString(key)
+			if err := w.writeValue(v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", value)
+	}
+	return nil
+}
+
+func (w *msgpackWriter) writeInt(v int64) {
+	switch {
+	case v >= 0:
+		w.writeUint(uint64(v))
+	case v >= -32:
+		w.buf = append(w.buf, byte(v))
+	case v >= math.MinInt8:
+		w.buf = append(w.buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xd2), uint32(v))
+	default:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xd3), uint64(v))
+	}
+}
+
+func (w *msgpackWriter) writeUint(v uint64) {
+	switch {
+	case v <= math.MaxInt8:
+		w.buf = append(w.buf, byte(v))
+	case v <= math.MaxUint8:
+		w.buf = append(w.buf, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xce), uint32(v))
+	default:
+		w.buf = binary.BigEndian.AppendUint64(append(w.buf, 0xcf), v)
+	}
+}
+
+func (w *msgpackWriter) writeString(v string) {
+	switch n := len(v); {
+	case n < 32:
+		w.buf = append(w.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xda), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xdb), uint32(n))
+	}
+	w.buf = append(w.buf, v...)
+}
+
+func (w *msgpackWriter) writeBinary(v []byte) {
+	switch n := len(v); {
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, 0xc5), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, 0xc6), uint32(n))
+	}
+	w.buf = append(w.buf, v...)
+}
+
+// writeHeader writes an array or map header: a fix form for lengths below fixLimit,
+// otherwise the 16- or 32-bit form.
+func (w *msgpackWriter) writeHeader(n int, fix byte, fixLimit int, code16, code32 byte) {
+	switch {
+	case n < fixLimit:
+		w.buf = append(w.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = binary.BigEndian.AppendUint16(append(w.buf, code16), uint16(n))
+	default:
+		w.buf = binary.BigEndian.AppendUint32(append(w.buf, code32), uint32(n))
+	}
+}
+
+// msgpackReader decodes MessagePack into the generic values msgpackWriter accepts.
+// Integers decode as int64, or uint64 when they exceed math.MaxInt64.
+type msgpackReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *msgpackReader) readValue() (any, error) {
+	code, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return r.readMap(int(code & 0x0f))
+	case code&0xf0 == 0x90:
+		return r.readArray(int(code & 0x0f))
+	case code&0xe0 == 0xa0:
+		return r.readString(int(code & 0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.readLength(code - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return slices.Clone(data), nil
+	case 0xca:
+		data, err := r.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 0xcb:
+		data, err := r.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := r.readUint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		v, err := r.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*size
+		return int64(v<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.readLength(code - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return r.readString(n)
+	case 0xdc, 0xdd:
+		n, err := r.readLength(code - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return r.readArray(n)
+	case 0xde, 0xdf:
+		n, err := r.readLength(code - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return r.readMap(n)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type code 0x%02x", code)
+}
+
+// readLength reads a 1-, 2-, or 4-byte length for width 0, 1, or 2.
+func (r *msgpackReader) readLength(width byte) (int, error) {
+	v, err := r.readUint(1 << width)
+	if err != nil {
+		return 0, err
+	}
+	return int(v), nil
+}
+
+func (r *msgpackReader) readUint(size int) (uint64, error) {
+	data, err := r.readBytes(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func (r *msgpackReader) readString(n int) (string, error) {
+	data, err := r.readBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (r *msgpackReader) readArray(n int) ([]any, error) {
+	// Every element takes at least one byte, which bounds allocation on corrupt input.
+	if n > len(r.data)-r.pos {
+		return nil, errMsgpackTruncated
+	}
+	items := make([]any, 0, n)
+	for range n {
+		item, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (r *msgpackReader) readMap(n int) (map[string]any, error) {
+	if 2*n > len(r.data)-r.pos {
+		return nil, errMsgpackTruncated
+	}
+	values := make(map[string]any, n)
+	for range n {
+		key, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: map key of type %T, want string", key)
+		}
+		if values[name], err = r.readValue(); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (r *msgpackReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errMsgpackTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *msgpackReader) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data)-r.pos {
+		return nil, errMsgpackTruncated
+	}
+	data := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return data, nil
+}
+
+// isMsgpackMap reports whether data starts with a MessagePack map header.
+func isMsgpackMap(data []byte) bool {
+	return len(data) > 0 && (data[0]&0xf0 == 0x80 || data[0] == 0xde || data[0] == 0xdf)
+}
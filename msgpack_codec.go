@@ -0,0 +1,298 @@
+package es
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// MsgPackCodec encodes events as MessagePack envelopes. Metadata is written
+// natively, with IDs as 16-byte binaries, and payloads are converted from the
+// event's JSON encoding, so json tags and custom JSON methods on event types keep
+// working. Like JSONCodec it can compress large payloads.
+type MsgPackCodec struct {
+	config codecConfig
+}
+
+// NewMsgPackCodec creates a MessagePack envelope codec.
+func NewMsgPackCodec(opts ...CodecOption) *MsgPackCodec {
+	codec := &MsgPackCodec{}
+	for _, opt := range opts {
+		opt(&codec.config)
+	}
+	return codec
+}
+
+// ContentType implements EventCodec.
+func (c *MsgPackCodec) ContentType() string { return ContentTypeMsgPack }
+
+// Marshal implements EventCodec.
+func (c *MsgPackCodec) Marshal(event DomainEvent) ([]byte, error) {
+	envelope, err := NewEnvelope(event)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := jsonToGeneric(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s payload: %w", envelope.Discriminator, err)
+	}
+
+	fields := map[string]any{
+		"content_type":  ContentTypeMsgPack,
+		"discriminator": envelope.Discriminator,
+		"metadata":      metadataToGeneric(envelope.Metadata),
+		"payload":       payload,
+	}
+
+	if compressor := c.config.compressor; compressor != nil {
+		var encoded msgpackWriter
+		if err := encoded.writeValue(payload); err != nil {
+			return nil, err
+		}
+		if len(encoded.buf) >= c.config.threshold {
+			compressed, err := compressor.Compress(encoded.buf)
+			if err != nil {
+				return nil, fmt.Errorf("compress %s payload: %w", envelope.Discriminator, err)
+			}
+			if len(compressed) < len(encoded.buf) {
+				fields["payload"] = compressed
+				fields["encoding"] = compressor.Encoding()
+			}
+		}
+	}
+
+	var w msgpackWriter
+	if err := w.writeValue(fields); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
+
+// Unmarshal implements EventCodec.
+func (c *MsgPackCodec) Unmarshal(data []byte) (DomainEvent, error) {
+	r := msgpackReader{data: data}
+	value, err := r.readValue()
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal msgpack envelope: %w", err)
+	}
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unmarshal msgpack envelope: got %T, want map", value)
+	}
+	if contentType, _ := fields["content_type"].(string); contentType != "" && contentType != ContentTypeMsgPack {
+		return nil, fmt.Errorf("unmarshal msgpack envelope: content type %q", contentType)
+	}
+
+	discriminator, _ := fields["discriminator"].(string)
+	metadata, err := metadataFromGeneric(fields["metadata"])
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %s metadata: %w", discriminator, err)
+	}
+
+	payload := fields["payload"]
+	if encoding, _ := fields["encoding"].(string); encoding != "" {
+		if payload, err = decompressMsgpackPayload(discriminator, encoding, payload); err != nil {
+			return nil, err
+		}
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %s payload: %w", discriminator, err)
+	}
+
+	return Envelope{Discriminator: discriminator, Metadata: metadata, Payload: raw}.Event()
+}
+
+func decompressMsgpackPayload(discriminator, encoding string, payload any) (any, error) {
+	compressor, ok := LookupCompressor(encoding)
+	if !ok {
+		return nil, wrapSentinelError(fmt.Sprintf("payload encoding %q of %s is not registered", encoding, discriminator), ErrUnknownEncoding)
+	}
+	compressed, ok := payload.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("decompress %s payload: got %T, want binary", discriminator, payload)
+	}
+	data, err := compressor.Decompress(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s payload: %w", discriminator, err)
+	}
+
+	r := msgpackReader{data: data}
+	value, err := r.readValue()
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s payload: %w", discriminator, err)
+	}
+	return value, nil
+}
+
+// msgpackContentType reads the content type tag of a MessagePack envelope, which
+// MsgPackCodec writes as the first entry because map keys are sorted.
+func msgpackContentType(data []byte) string {
+	r := msgpackReader{data: data}
+	code, err := r.readByte()
+	if err != nil {
+		return ""
+	}
+	if code == 0xde || code == 0xdf {
+		if _, err := r.readLength(code - 0xde + 1); err != nil {
+			return ""
+		}
+	}
+
+	key, err := r.readValue()
+	if err != nil || key != "content_type" {
+		return ""
+	}
+	contentType, _ := r.readValue()
+	value, _ := contentType.(string)
+	return value
+}
+
+// metadataToGeneric converts metadata for msgpackWriter. Nil IDs are omitted.
+func metadataToGeneric(metadata EventMetadata) map[string]any {
+	entity := map[string]any{
+		"id":    metadata.Entity.ID[:],
+		"area":  metadata.Entity.Area,
+		"scope": int64(metadata.Entity.Scope),
+	}
+	putID(entity, "tenant_id", metadata.Entity.TenantID)
+
+	fields := map[string]any{
+		"entity":    entity,
+		"timestamp": metadata.Timestamp,
+		"sequence":  metadata.Sequence,
+	}
+	putID(fields, "event_id", metadata.EventID)
+	putID(fields, "correlation_id", metadata.CorrelationID)
+	putID(fields, "causation_id", metadata.CausationID)
+	return fields
+}
+
+func putID(fields map[string]any, key string, id uuid.UUID) {
+	if id != uuid.Nil {
+		fields[key] = id[:]
+	}
+}
+
+// metadataFromGeneric is the inverse of metadataToGeneric. Like Entity.UnmarshalJSON
+// it falls back to the legacy "space" and "ID" entity fields, and it accepts IDs
+// written as strings.
+func metadataFromGeneric(value any) (EventMetadata, error) {
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return EventMetadata{}, fmt.Errorf("got %T, want map", value)
+	}
+
+	var metadata EventMetadata
+	var err error
+	if entity, ok := fields["entity"].(map[string]any); ok {
+		if metadata.Entity, err = entityFromGeneric(entity); err != nil {
+			return EventMetadata{}, err
+		}
+	}
+	if metadata.EventID, err = idFromGeneric(fields["event_id"]); err != nil {
+		return EventMetadata{}, err
+	}
+	if metadata.CorrelationID, err = idFromGeneric(fields["correlation_id"]); err != nil {
+		return EventMetadata{}, err
+	}
+	if metadata.CausationID, err = idFromGeneric(fields["causation_id"]); err != nil {
+		return EventMetadata{}, err
+	}
+	timestamp, _ := fields["timestamp"].(int64)
+	metadata.Timestamp = timestamp
+	metadata.Sequence = uintFromGeneric(fields["sequence"])
+	return metadata, nil
+}
+
+func entityFromGeneric(fields map[string]any) (Entity, error) {
+	var entity Entity
+	var err error
+	if entity.ID, err = idFromGeneric(fields["id"]); err != nil {
+		return Entity{}, err
+	}
+	if entity.ID == uuid.Nil {
+		if entity.ID, err = idFromGeneric(fields["ID"]); err != nil {
+			return Entity{}, err
+		}
+	}
+	if entity.TenantID, err = idFromGeneric(fields["tenant_id"]); err != nil {
+		return Entity{}, err
+	}
+	entity.Area, _ = fields["area"].(string)
+	if entity.Area == "" {
+		entity.Area, _ = fields["space"].(string)
+	}
+	scope, _ := fields["scope"].(int64)
+	entity.Scope = Scope(scope)
+	return entity, nil
+}
+
+func idFromGeneric(value any) (uuid.UUID, error) {
+	switch v := value.(type) {
+	case nil:
+		return uuid.Nil, nil
+	case []byte:
+		return uuid.FromBytes(v)
+	case string:
+		return uuid.Parse(v)
+	}
+	return uuid.Nil, fmt.Errorf("id of type %T", value)
+}
+
+func uintFromGeneric(value any) uint64 {
+	switch v := value.(type) {
+	case int64:
+		if v > 0 {
+			return uint64(v)
+		}
+	case uint64:
+		return v
+	}
+	return 0
+}
+
+// jsonToGeneric decodes JSON into msgpackWriter values. Numbers keep their exact
+// value: integers decode as int64 or uint64, everything else as float64.
+func jsonToGeneric(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return convertJSONNumbers(value)
+}
+
+func convertJSONNumbers(value any) (any, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		return strconv.ParseFloat(v.String(), 64)
+	case []any:
+		for i, item := range v {
+			converted, err := convertJSONNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+	case map[string]any:
+		for key, item := range v {
+			converted, err := convertJSONNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+	}
+	return value, nil
+}
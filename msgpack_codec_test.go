@@ -0,0 +1,208 @@
+package es
+
+import (
+	"compress/gzip"
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MeasurementRecorded struct {
+	DomainEventBase
+	Source   Entity            `json:"source"`
+	Count    uint64            `json:"count"`
+	Delta    int64             `json:"delta"`
+	Ratio    float64           `json:"ratio"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Raw      []byte            `json:"raw"`
+	Optional *string           `json:"optional"`
+}
+
+func (e *MeasurementRecorded) GetDiscriminator() string { return "measurement_recorded" }
+func (e *MeasurementRecorded) GetAreas() []string       { return []string{AreaDummy} }
+func (e *MeasurementRecorded) GetSpaces() []string      { return e.GetAreas() }
+
+func init() {
+	RegisterEvent[*MeasurementRecorded]()
+}
+
+func newMeasurementRecorded() *MeasurementRecorded {
+	tenantID := uuid.New()
+	event := &MeasurementRecorded{
+		Source: NewTenantEntity(tenantID, uuid.New(), AreaTest),
+		Count:  math.MaxUint64,
+		Delta:  math.MinInt64,
+		Ratio:  0.1,
+		Tags:   []string{"a", "b"},
+		Labels: map[string]string{"unit": "ms"},
+		Raw:    []byte{0, 1, 2},
+	}
+	event.SetMetadata(EventMetadata{
+		Entity:        NewTenantEntity(tenantID, uuid.New(), AreaDummy),
+		EventID:       uuid.New(),
+		CorrelationID: uuid.New(),
+		CausationID:   uuid.New(),
+		Timestamp:     1700000000123,
+		Sequence:      42,
+	})
+	return event
+}
+
+func TestShouldRoundTripEventThroughMsgPackCodec(t *testing.T) {
+	// Arrange
+	codec := NewMsgPackCodec()
+	event := newMeasurementRecorded()
+
+	// Act
+	data, err := codec.Marshal(event)
+	require.NoError(t, err)
+	decoded, err := codec.Unmarshal(data)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, event, decoded)
+	assert.Equal(t, ContentTypeMsgPack, DetectContentType(data))
+}
+
+func TestShouldWriteSmallerEnvelopesWithMsgPackThanJSON(t *testing.T) {
+	// Arrange
+	event := newMeasurementRecorded()
+
+	// Act
+	binary, err := NewMsgPackCodec().Marshal(event)
+	require.NoError(t, err)
+	text, err := NewJSONCodec().Marshal(event)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Less(t, len(binary), len(text))
+}
+
+func TestShouldDecodeLegacyEntityFieldsFromMsgPack(t *testing.T) {
+	// Arrange
+	id := uuid.New()
+	eventID := uuid.New()
+	var w msgpackWriter
+	require.NoError(t, w.writeValue(map[string]any{
+		"content_type":  ContentTypeMsgPack,
+		"discriminator": "dummy_created",
+		"metadata": map[string]any{
+			"entity":   map[string]any{"ID": id.String(), "space": AreaDummy},
+			"event_id": eventID[:],
+			"sequence": int64(1),
+		},
+		"payload": map[string]any{"Name": "legacy"},
+	}))
+
+	// Act
+	decoded, err := NewMsgPackCodec().Unmarshal(w.buf)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, NewEntity(id, AreaDummy), decoded.GetEntity())
+	assert.Equal(t, eventID, decoded.GetEventID())
+	assert.Equal(t, "legacy", decoded.(*DummyCreated).Name)
+}
+
+func TestShouldCompressLargeMsgPackPayloads(t *testing.T) {
+	// Arrange
+	codec := NewMsgPackCodec(WithCompression(NewGzipCompressor(gzip.DefaultCompression), 512))
+	event := newLargeAuditEvent(NewEntity(uuid.New(), AreaDummy))
+
+	// Act
+	data, err := codec.Marshal(event)
+	require.NoError(t, err)
+	decoded, err := codec.Unmarshal(data)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Less(t, len(data), len(event.Reason)/4)
+	assert.Equal(t, event, decoded)
+}
+
+func TestShouldReadMixedFormatsFromOneStore(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 2)
+	jsonStore := NewInMemoryEventStore(WithStoreCodec(NewJSONCodec()))
+	require.NoError(t, jsonStore.SaveEvents(ctx, entity, events[:1], 0))
+	mixed := NewMixedCodec(NewMsgPackCodec())
+	inner := jsonStore.(*InMemoryEventStore)
+	inner.codec = mixed
+
+	// Act
+	err := inner.SaveEvents(ctx, entity, events[1:], 1)
+
+	// Assert
+	require.NoError(t, err)
+	loaded, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events, loaded)
+}
+
+func TestShouldDetectEnvelopeContentTypes(t *testing.T) {
+	// Arrange
+	event := newDummyEvents(NewEntity(uuid.New(), AreaDummy), 1)[0]
+	data, err := NewJSONCodec().Marshal(event)
+	require.NoError(t, err)
+
+	// Act & Assert
+	assert.Equal(t, ContentTypeJSON, DetectContentType(data))
+	assert.Equal(t, ContentTypeJSON, DetectContentType([]byte(`{"discriminator":"dummy_created"}`)))
+	assert.Empty(t, DetectContentType([]byte("plain text")))
+}
+
+func TestShouldRejectUnregisteredContentType(t *testing.T) {
+	// Arrange
+	codec := NewMixedCodec(NewJSONCodec())
+
+	// Act
+	_, err := codec.Unmarshal([]byte(`{"content_type":"application/cbor"}`))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnknownEncoding)
+}
+
+func TestShouldRejectTruncatedMsgPack(t *testing.T) {
+	// Arrange
+	data, err := NewMsgPackCodec().Marshal(newMeasurementRecorded())
+	require.NoError(t, err)
+
+	// Act
+	_, err = NewMsgPackCodec().Unmarshal(data[:len(data)/2])
+
+	// Assert
+	assert.ErrorContains(t, err, "unexpected end of input")
+}
+
+func TestShouldRoundTripMsgPackScalars(t *testing.T) {
+	// Arrange
+	values := []any{
+		nil, true, false,
+		int64(0), int64(127), int64(-1), int64(-32), int64(-33), int64(math.MinInt8), int64(math.MinInt16),
+		int64(math.MinInt32), int64(math.MinInt64), int64(255), int64(65535), int64(math.MaxUint32), uint64(math.MaxUint64),
+		1.5, "", strings.Repeat("s", 40), strings.Repeat("s", 300), strings.Repeat("s", 70000),
+		[]byte{1, 2, 3}, make([]byte, 300), make([]byte, 70000),
+		[]any{int64(1), "two"}, map[string]any{"k": []any{}},
+	}
+
+	for _, value := range values {
+		// Act
+		var w msgpackWriter
+		require.NoError(t, w.writeValue(value))
+		r := msgpackReader{data: w.buf}
+		decoded, err := r.readValue()
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, value, decoded)
+		assert.Equal(t, len(w.buf), r.pos)
+	}
+}
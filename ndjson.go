@@ -0,0 +1,41 @@
+package es
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// ReadEnvelopes reads newline-delimited JSON envelopes, as written by JSONCodec
+// one per line, skipping blank lines. Payloads are returned as stored; call
+// Envelope.Event or Envelope.Decompress to use them. Iteration stops at the
+// first malformed line.
+func ReadEnvelopes(r io.Reader) iter.Seq2[Envelope, error] {
+	return func(yield func(Envelope, error) bool) {
+		reader := bufio.NewReader(r)
+		for line := 1; ; line++ {
+			data, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(data)) > 0 {
+				var envelope Envelope
+				if jsonErr := json.Unmarshal(data, &envelope); jsonErr != nil {
+					yield(Envelope{}, fmt.Errorf("line %d: %w", line, jsonErr))
+					return
+				}
+				if !yield(envelope, nil) {
+					return
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(Envelope{}, err)
+				return
+			}
+		}
+	}
+}
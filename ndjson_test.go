@@ -0,0 +1,43 @@
+package es
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReadEnvelopesSkippingBlankLines(t *testing.T) {
+	// Arrange
+	input := `{"discriminator":"dummy_created","payload":{"Name":"a"}}
+
+{"discriminator":"dummy_created","payload":{"Name":"b"}}`
+
+	// Act
+	var envelopes []Envelope
+	for envelope, err := range ReadEnvelopes(strings.NewReader(input)) {
+		require.NoError(t, err)
+		envelopes = append(envelopes, envelope)
+	}
+
+	// Assert
+	require.Len(t, envelopes, 2)
+	assert.JSONEq(t, `{"Name":"b"}`, string(envelopes[1].Payload))
+}
+
+func TestShouldReportLineOfMalformedEnvelope(t *testing.T) {
+	// Arrange
+	input := "{\"discriminator\":\"dummy_created\"}\n{oops\n"
+
+	// Act
+	var err error
+	for _, err = range ReadEnvelopes(strings.NewReader(input)) {
+		if err != nil {
+			break
+		}
+	}
+
+	// Assert
+	assert.ErrorContains(t, err, "line 2")
+}
@@ -0,0 +1,431 @@
+package es
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	errHandleProcessEventAlreadyExists = "HandleProcessEvent: process %s already handles event %s"
+	errHandleProcessTimeoutExists      = "HandleProcessTimeout: process %s already handles timeout %q"
+	errNewProcessManagerEmptyArea      = "NewProcessManager: area cannot be empty"
+	errNewProcessManagerNilFactory     = "NewProcessManager: factory must not be nil"
+	errProcessNoTimeoutScheduler       = "process manager %s has no TimeoutScheduler"
+
+	processTimeoutDiscriminator = "es://process_timeout"
+)
+
+// CommandDispatcher delivers commands issued by process managers to their handlers.
+type CommandDispatcher interface {
+	Dispatch(ctx context.Context, command any) error
+}
+
+// CommandDispatcherFunc adapts a function to the CommandDispatcher interface.
+type CommandDispatcherFunc func(ctx context.Context, command any) error
+
+// Dispatch implements CommandDispatcher.
+func (f CommandDispatcherFunc) Dispatch(ctx context.Context, command any) error {
+	return f(ctx, command)
+}
+
+// ProcessTimeout is delivered to a process manager when a timeout requested with
+// ProcessStep.ScheduleTimeout is due. Its metadata entity is the process instance
+// and its causation is the event that requested it.
+type ProcessTimeout struct {
+	DomainEventBase
+	Name string `json:"name"`
+	Due  int64  `json:"due"`
+}
+
+// GetDiscriminator implements polymorphic.Polymorphic.
+func (e *ProcessTimeout) GetDiscriminator() string { return processTimeoutDiscriminator }
+
+// GetAreas returns the area of the process the timeout belongs to.
+func (e *ProcessTimeout) GetAreas() []string { return []string{e.Metadata.Entity.Area} }
+
+// GetSpaces returns the same values as GetAreas.
+func (e *ProcessTimeout) GetSpaces() []string { return e.GetAreas() }
+
+// TimeoutScheduler keeps process timeouts until they are due and then delivers each
+// one to the ProcessManager.Handle of the process that requested it.
+type TimeoutScheduler interface {
+	ScheduleTimeout(ctx context.Context, timeout *ProcessTimeout) error
+}
+
+// ProcessStep collects the effects of handling one event: commands to dispatch and
+// timeouts to schedule. They are carried out only when the handler succeeds.
+type ProcessStep struct {
+	commands []any
+	timeouts []processTimeoutRequest
+}
+
+type processTimeoutRequest struct {
+	name  string
+	delay int64
+}
+
+// Dispatch queues a command for the process manager's CommandDispatcher.
+func (s *ProcessStep) Dispatch(command any) {
+	s.commands = append(s.commands, command)
+}
+
+// ScheduleTimeout requests a ProcessTimeout with the given name after delay, in the
+// units of the process manager's Clock. Timeouts cannot be cancelled; a timeout
+// handler should check the process state and ignore timeouts that no longer apply.
+func (s *ProcessStep) ScheduleTimeout(name string, delay int64) {
+	s.timeouts = append(s.timeouts, processTimeoutRequest{name: name, delay: delay})
+}
+
+// ProcessFactory creates an empty process instance with the given ID. The trigger is
+// the event being handled, for factories that need its tenant or scope.
+type ProcessFactory[P Aggregate] func(ctx context.Context, id uuid.UUID, trigger DomainEvent) P
+
+// ProcessRouteOption configures how HandleProcessEvent routes an event type.
+type ProcessRouteOption func(*processRoute)
+
+// StartsProcess lets the event create a new process instance. Events without it are
+// ignored when no instance exists yet.
+func StartsProcess() ProcessRouteOption {
+	return func(r *processRoute) {
+		r.starts = true
+	}
+}
+
+// CorrelateBy routes events to the process instance whose ID is returned by key,
+// typically an ID in the event payload. By default events are routed by their
+// CorrelationID. Events for which key returns uuid.Nil are ignored.
+func CorrelateBy[T DomainEvent](key func(T) uuid.UUID) ProcessRouteOption {
+	return func(r *processRoute) {
+		r.correlate = func(event DomainEvent) uuid.UUID {
+			typed, ok := event.(T)
+			if !ok {
+				return uuid.Nil
+			}
+			return key(typed)
+		}
+	}
+}
+
+type processRoute struct {
+	starts    bool
+	correlate func(DomainEvent) uuid.UUID
+	handle    func(ctx context.Context, process Aggregate, event DomainEvent, step *ProcessStep) error
+}
+
+func correlateByCorrelationID(event DomainEvent) uuid.UUID {
+	return event.GetCorrelationID()
+}
+
+// ProcessManagerOption configures a ProcessManager.
+type ProcessManagerOption func(*processManagerOptions)
+
+type processManagerOptions struct {
+	clock    Clock
+	timeouts TimeoutScheduler
+}
+
+// WithProcessClock sets the clock used to compute timeout due times.
+func WithProcessClock(clock Clock) ProcessManagerOption {
+	return func(o *processManagerOptions) {
+		o.clock = clock
+	}
+}
+
+// WithTimeoutScheduler sets the scheduler for timeouts requested by handlers.
+// Without one, ProcessStep.ScheduleTimeout makes Handle fail.
+func WithTimeoutScheduler(scheduler TimeoutScheduler) ProcessManagerOption {
+	return func(o *processManagerOptions) {
+		o.timeouts = scheduler
+	}
+}
+
+// ProcessManager coordinates a long-running workflow, such as order, payment, and
+// shipping, as an event-sourced process. Each instance is an aggregate of type P in
+// the manager's area, persisted through a Repository. The manager reacts to events
+// by discriminator, routes each one to an instance, runs its handler, and carries
+// out the commands and timeouts the handler requests.
+//
+// Handlers record progress by raising events on the process. Events raised while
+// handling an event carry that event's ID as their causation, and an event already
+// recorded as a cause is skipped on redelivery. Commands are dispatched and timeouts
+// scheduled before the process is saved, so a failure at any point is retried when
+// the event is delivered again; command handlers should therefore be idempotent.
+type ProcessManager[P Aggregate] struct {
+	area       string
+	repository Repository
+	dispatcher CommandDispatcher
+	factory    ProcessFactory[P]
+	options    processManagerOptions
+
+	mu     sync.RWMutex
+	routes map[string]processRoute
+	// timeoutHandlers holds the HandleProcessTimeout handlers by timeout name.
+	timeoutHandlers map[string]func(ctx context.Context, process Aggregate, timeout *ProcessTimeout, step *ProcessStep) error
+}
+
+// NewProcessManager creates a process manager for processes in area. It panics when
+// area is empty or factory is nil.
+func NewProcessManager[P Aggregate](area string, repository Repository, dispatcher CommandDispatcher, factory ProcessFactory[P], opts ...ProcessManagerOption) *ProcessManager[P] {
+	if area == "" {
+		panic(errNewProcessManagerEmptyArea)
+	}
+	if factory == nil {
+		panic(errNewProcessManagerNilFactory)
+	}
+
+	options := processManagerOptions{clock: systemClock{}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &ProcessManager[P]{
+		area:            area,
+		repository:      repository,
+		dispatcher:      dispatcher,
+		factory:         factory,
+		options:         options,
+		routes:          make(map[string]processRoute),
+		timeoutHandlers: make(map[string]func(context.Context, Aggregate, *ProcessTimeout, *ProcessStep) error),
+	}
+}
+
+// HandleProcessEvent registers the handler the process manager runs for events of
+// type T. It panics when the event type already has a handler or is not concrete.
+func HandleProcessEvent[P Aggregate, T DomainEvent](pm *ProcessManager[P], handler func(ctx context.Context, process P, event T, step *ProcessStep) error, opts ...ProcessRouteOption) {
+	if handler == nil {
+		panic(errRegisterHandlerNilHandler)
+	}
+	var expected DomainEvent = newEventInstance[T]()
+
+	route := processRoute{
+		correlate: correlateByCorrelationID,
+		handle: func(ctx context.Context, process Aggregate, event DomainEvent, step *ProcessStep) error {
+			typed, ok := event.(T)
+			if !ok {
+				panic(fmt.Sprintf(errRegisterHandlerTypeMismatch, event, expected))
+			}
+			return handler(ctx, process.(P), typed, step)
+		},
+	}
+	for _, opt := range opts {
+		opt(&route)
+	}
+	pm.addRoute(expected.GetDiscriminator(), route)
+}
+
+// HandleProcessTimeout registers the handler the process manager runs when the
+// timeout with the given name is due. It panics when the name already has a handler.
+func HandleProcessTimeout[P Aggregate](pm *ProcessManager[P], name string, handler func(ctx context.Context, process P, timeout *ProcessTimeout, step *ProcessStep) error) {
+	if handler == nil {
+		panic(errRegisterHandlerNilHandler)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if _, exists := pm.timeoutHandlers[name]; exists {
+		panic(fmt.Sprintf(errHandleProcessTimeoutExists, pm.area, name))
+	}
+	pm.timeoutHandlers[name] = func(ctx context.Context, process Aggregate, timeout *ProcessTimeout, step *ProcessStep) error {
+		return handler(ctx, process.(P), timeout, step)
+	}
+	pm.routes[processTimeoutDiscriminator] = processRoute{
+		correlate: pm.correlateTimeout,
+		handle:    pm.handleTimeout,
+	}
+}
+
+func (pm *ProcessManager[P]) addRoute(discriminator string, route processRoute) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if _, exists := pm.routes[discriminator]; exists {
+		panic(fmt.Sprintf(errHandleProcessEventAlreadyExists, pm.area, discriminator))
+	}
+	pm.routes[discriminator] = route
+}
+
+// correlateTimeout routes a timeout to the process that requested it, ignoring
+// timeouts of other process areas.
+func (pm *ProcessManager[P]) correlateTimeout(event DomainEvent) uuid.UUID {
+	if event.GetEntity().Area != pm.area {
+		return uuid.Nil
+	}
+	return event.GetAggregateID()
+}
+
+func (pm *ProcessManager[P]) handleTimeout(ctx context.Context, process Aggregate, event DomainEvent, step *ProcessStep) error {
+	timeout := event.(*ProcessTimeout)
+	pm.mu.RLock()
+	handler, ok := pm.timeoutHandlers[timeout.Name]
+	pm.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return handler(ctx, process, timeout, step)
+}
+
+// Area returns the area of the manager's process streams.
+func (pm *ProcessManager[P]) Area() string {
+	return pm.area
+}
+
+// Discriminators returns the sorted discriminators of the events the manager
+// handles, for subscribing it to an event feed.
+func (pm *ProcessManager[P]) Discriminators() []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return slices.Sorted(maps.Keys(pm.routes))
+}
+
+// Handle delivers an event to the process manager. Events without a handler, events
+// that do not correlate to a process, and events that would continue a process that
+// does not exist are ignored. Handle is safe for concurrent use; concurrent updates
+// of the same process fail with ErrConcurrency and can be retried. The process is
+// saved before its timeouts are scheduled and its commands dispatched, so a failed
+// save has no effects, while an error after the save is not retried on redelivery.
+func (pm *ProcessManager[P]) Handle(ctx context.Context, event DomainEvent) error {
+	pm.mu.RLock()
+	route, ok := pm.routes[event.GetDiscriminator()]
+	pm.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	id := route.correlate(event)
+	if id == uuid.Nil {
+		return nil
+	}
+
+	ctx = WithEventMetadata(ctx, event)
+	var process Aggregate = pm.factory(ctx, id, event)
+	entity := process.GetEntity()
+	ctx, span := startSpan(ctx, spanProcessHandle, entity,
+		attribute.String(attributeEventDiscriminator, event.GetDiscriminator()),
+	)
+	defer span.End()
+
+	if err := pm.repository.Load(ctx, process); err != nil {
+		return recordSpanError(span, err)
+	}
+	if process.GetCommittedSequence() == 0 && !route.starts {
+		return nil
+	}
+	if processHandled(process, event) {
+		return nil
+	}
+
+	step := &ProcessStep{}
+	if err := route.handle(ctx, process, event, step); err != nil {
+		return recordSpanError(span, err)
+	}
+	if len(step.timeouts) > 0 && pm.options.timeouts == nil {
+		return recordSpanError(span, fmt.Errorf(errProcessNoTimeoutScheduler, pm.area))
+	}
+	// The process is saved before its effects are carried out, so a save that fails
+	// with ErrConcurrency and is retried does not dispatch commands twice.
+	if err := pm.repository.Save(ctx, process); err != nil {
+		return recordSpanError(span, err)
+	}
+	if err := pm.scheduleTimeouts(ctx, entity, event, step.timeouts); err != nil {
+		return recordSpanError(span, err)
+	}
+	for _, command := range step.commands {
+		if err := pm.dispatcher.Dispatch(ctx, command); err != nil {
+			return recordSpanError(span, fmt.Errorf("dispatch %T: %w", command, err))
+		}
+	}
+	return nil
+}
+
+// processHandled reports whether the process already recorded an event caused by event.
+func processHandled(process Aggregate, event DomainEvent) bool {
+	eventID := event.GetEventID()
+	if eventID == uuid.Nil {
+		return false
+	}
+	return slices.ContainsFunc(process.GetCommittedEvents(), func(committed DomainEvent) bool {
+		return committed.GetCausationID() == eventID
+	})
+}
+
+// scheduleTimeouts schedules the timeouts requested while handling event. Timeout
+// IDs derive from the event ID, name, and position, so scheduling the timeouts of
+// an event again yields the same IDs and schedulers can drop the duplicates.
+func (pm *ProcessManager[P]) scheduleTimeouts(ctx context.Context, entity Entity, event DomainEvent, requests []processTimeoutRequest) error {
+	ids := GetIDGenerator(ctx)
+	now := pm.options.clock.GetTimestamp()
+	for i, request := range requests {
+		eventID := ids.NewID()
+		if trigger := event.GetEventID(); trigger != uuid.Nil {
+			eventID = uuid.NewSHA1(trigger, fmt.Appendf(nil, "%s/%d", request.name, i))
+		}
+		timeout := &ProcessTimeout{Name: request.name, Due: now + request.delay}
+		timeout.SetMetadata(EventMetadata{
+			Entity:        entity,
+			EventID:       eventID,
+			CorrelationID: GetCorrelationID(ctx),
+			CausationID:   GetCausationID(ctx),
+			Timestamp:     now,
+		})
+		if err := pm.options.timeouts.ScheduleTimeout(ctx, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InMemoryTimeoutScheduler holds process timeouts in memory until FireDue delivers
+// them. Timeouts do not survive a restart. It is safe for concurrent use.
+type InMemoryTimeoutScheduler struct {
+	clock   Clock
+	mu      sync.Mutex
+	pending []*ProcessTimeout
+}
+
+// NewInMemoryTimeoutScheduler creates a scheduler that compares due times against clock.
+func NewInMemoryTimeoutScheduler(clock Clock) *InMemoryTimeoutScheduler {
+	return &InMemoryTimeoutScheduler{clock: clock}
+}
+
+// ScheduleTimeout implements TimeoutScheduler.
+func (s *InMemoryTimeoutScheduler) ScheduleTimeout(_ context.Context, timeout *ProcessTimeout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, timeout)
+	return nil
+}
+
+// FireDue delivers the timeouts that are due, earliest first, to handle, which is
+// usually a ProcessManager's Handle method. It stops at the first error and keeps
+// that timeout and later ones scheduled. It returns the number delivered.
+func (s *InMemoryTimeoutScheduler) FireDue(ctx context.Context, handle func(context.Context, DomainEvent) error) (int, error) {
+	now := s.clock.GetTimestamp()
+	s.mu.Lock()
+	slices.SortStableFunc(s.pending, func(a, b *ProcessTimeout) int { return cmp.Compare(a.Due, b.Due) })
+	dueCount, _ := slices.BinarySearchFunc(s.pending, now+1, func(t *ProcessTimeout, target int64) int {
+		return cmp.Compare(t.Due, target)
+	})
+	due := slices.Clone(s.pending[:dueCount])
+	s.mu.Unlock()
+
+	fired := 0
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.pending = slices.DeleteFunc(s.pending, func(t *ProcessTimeout) bool {
+			return slices.Contains(due[:fired], t)
+		})
+	}()
+	for _, timeout := range due {
+		if err := handle(ctx, timeout); err != nil {
+			return fired, err
+		}
+		fired++
+	}
+	return fired, nil
+}
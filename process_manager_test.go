@@ -0,0 +1,284 @@
+package es
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const AreaFulfillment string = "fulfillment"
+
+type OrderPlaced struct {
+	DomainEventBase
+	OrderID uuid.UUID
+}
+
+func (e *OrderPlaced) GetDiscriminator() string { return "order_placed" }
+func (e *OrderPlaced) GetAreas() []string       { return []string{AreaDummy} }
+func (e *OrderPlaced) GetSpaces() []string      { return e.GetAreas() }
+
+type PaymentCaptured struct {
+	DomainEventBase
+	OrderID uuid.UUID
+}
+
+func (e *PaymentCaptured) GetDiscriminator() string { return "payment_captured" }
+func (e *PaymentCaptured) GetAreas() []string       { return []string{AreaDummy} }
+func (e *PaymentCaptured) GetSpaces() []string      { return e.GetAreas() }
+
+type FulfillmentStepRecorded struct {
+	DomainEventBase
+	Step string
+}
+
+func (e *FulfillmentStepRecorded) GetDiscriminator() string { return "fulfillment_step_recorded" }
+func (e *FulfillmentStepRecorded) GetAreas() []string       { return []string{AreaFulfillment} }
+func (e *FulfillmentStepRecorded) GetSpaces() []string      { return e.GetAreas() }
+
+type RequestPayment struct{ OrderID uuid.UUID }
+
+type ShipOrder struct{ OrderID uuid.UUID }
+
+type CancelOrder struct{ OrderID uuid.UUID }
+
+type Fulfillment struct {
+	Aggregate
+	steps []string
+}
+
+func NewFulfillment(ctx context.Context, id uuid.UUID, _ DomainEvent) *Fulfillment {
+	f := &Fulfillment{Aggregate: NewAggregate(ctx, AreaFulfillment, id)}
+	RegisterHandler(f, func(e *FulfillmentStepRecorded) { f.steps = append(f.steps, e.Step) })
+	return f
+}
+
+func (f *Fulfillment) Record(step string) error {
+	return f.Raise(&FulfillmentStepRecorded{Step: step})
+}
+
+func (f *Fulfillment) Paid() bool {
+	for _, step := range f.steps {
+		if step == "paid" {
+			return true
+		}
+	}
+	return false
+}
+
+type recordingDispatcher struct {
+	commands []any
+	contexts []context.Context
+}
+
+func (d *recordingDispatcher) Dispatch(ctx context.Context, command any) error {
+	d.commands = append(d.commands, command)
+	d.contexts = append(d.contexts, ctx)
+	return nil
+}
+
+func newFulfillmentManager(repository Repository, dispatcher CommandDispatcher, opts ...ProcessManagerOption) *ProcessManager[*Fulfillment] {
+	pm := NewProcessManager(AreaFulfillment, repository, dispatcher, NewFulfillment, opts...)
+	HandleProcessEvent(pm, func(_ context.Context, f *Fulfillment, e *OrderPlaced, step *ProcessStep) error {
+		step.Dispatch(RequestPayment{OrderID: e.OrderID})
+		step.ScheduleTimeout("payment", 100)
+		return f.Record("payment_requested")
+	}, StartsProcess(), CorrelateBy(func(e *OrderPlaced) uuid.UUID { return e.OrderID }))
+	HandleProcessEvent(pm, func(_ context.Context, f *Fulfillment, e *PaymentCaptured, step *ProcessStep) error {
+		step.Dispatch(ShipOrder{OrderID: e.OrderID})
+		return f.Record("paid")
+	}, CorrelateBy(func(e *PaymentCaptured) uuid.UUID { return e.OrderID }))
+	HandleProcessTimeout(pm, "payment", func(_ context.Context, f *Fulfillment, _ *ProcessTimeout, step *ProcessStep) error {
+		if f.Paid() {
+			return nil
+		}
+		step.Dispatch(CancelOrder{OrderID: f.GetAggregateID()})
+		return f.Record("cancelled")
+	})
+	return pm
+}
+
+func newTriggerEvent[T DomainEvent](event T, correlationID uuid.UUID) T {
+	event.SetMetadata(EventMetadata{
+		Entity:        NewEntity(uuid.New(), AreaDummy),
+		EventID:       uuid.New(),
+		CorrelationID: correlationID,
+		Sequence:      1,
+	})
+	return event
+}
+
+func TestShouldStartAndContinueProcessByPayloadKey(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	repository := NewRepository(NewInMemoryEventStore())
+	dispatcher := &recordingDispatcher{}
+	pm := newFulfillmentManager(repository, dispatcher, WithTimeoutScheduler(NewInMemoryTimeoutScheduler(NewFakeClock(0))))
+	orderID := uuid.New()
+	placed := newTriggerEvent(&OrderPlaced{OrderID: orderID}, uuid.New())
+	captured := newTriggerEvent(&PaymentCaptured{OrderID: orderID}, uuid.New())
+
+	// Act
+	require.NoError(t, pm.Handle(ctx, placed))
+	require.NoError(t, pm.Handle(ctx, captured))
+
+	// Assert
+	assert.Equal(t, []any{RequestPayment{OrderID: orderID}, ShipOrder{OrderID: orderID}}, dispatcher.commands)
+	assert.Equal(t, placed.GetEventID(), GetCausationID(dispatcher.contexts[0]))
+	assert.Equal(t, placed.GetCorrelationID(), GetCorrelationID(dispatcher.contexts[0]))
+	process := NewFulfillment(ctx, orderID, nil)
+	require.NoError(t, repository.Load(ctx, process))
+	assert.Equal(t, []string{"payment_requested", "paid"}, process.steps)
+	assert.Equal(t, captured.GetEventID(), process.GetCommittedEvents()[1].GetCausationID())
+}
+
+func TestShouldIgnoreRedeliveredProcessEvent(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dispatcher := &recordingDispatcher{}
+	pm := newFulfillmentManager(NewRepository(NewInMemoryEventStore()), dispatcher, WithTimeoutScheduler(NewInMemoryTimeoutScheduler(NewFakeClock(0))))
+	placed := newTriggerEvent(&OrderPlaced{OrderID: uuid.New()}, uuid.New())
+	require.NoError(t, pm.Handle(ctx, placed))
+
+	// Act
+	err := pm.Handle(ctx, placed)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, dispatcher.commands, 1)
+}
+
+func TestShouldIgnoreEventsForProcessesThatHaveNotStarted(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	dispatcher := &recordingDispatcher{}
+	pm := newFulfillmentManager(NewRepository(store), dispatcher)
+	captured := newTriggerEvent(&PaymentCaptured{OrderID: uuid.New()}, uuid.New())
+
+	// Act
+	err := pm.Handle(ctx, captured)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, dispatcher.commands)
+	page, err := store.ListStreams(ctx, StreamFilter{Area: AreaFulfillment})
+	require.NoError(t, err)
+	assert.Empty(t, page.Entities)
+}
+
+func TestShouldDeliverProcessTimeoutsWhenDue(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	clock := NewFakeClock(1000)
+	scheduler := NewInMemoryTimeoutScheduler(clock)
+	dispatcher := &recordingDispatcher{}
+	pm := newFulfillmentManager(NewRepository(NewInMemoryEventStore()), dispatcher,
+		WithProcessClock(clock), WithTimeoutScheduler(scheduler))
+	orderID := uuid.New()
+	require.NoError(t, pm.Handle(ctx, newTriggerEvent(&OrderPlaced{OrderID: orderID}, uuid.New())))
+
+	// Act
+	early, earlyErr := scheduler.FireDue(ctx, pm.Handle)
+	clock.Advance(100)
+	due, dueErr := scheduler.FireDue(ctx, pm.Handle)
+	again, againErr := scheduler.FireDue(ctx, pm.Handle)
+
+	// Assert
+	require.NoError(t, earlyErr)
+	require.NoError(t, dueErr)
+	require.NoError(t, againErr)
+	assert.Equal(t, 0, early)
+	assert.Equal(t, 1, due)
+	assert.Equal(t, 0, again)
+	assert.Equal(t, []any{RequestPayment{OrderID: orderID}, CancelOrder{OrderID: orderID}}, dispatcher.commands)
+}
+
+func TestShouldCorrelateProcessByCorrelationIDByDefault(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dispatcher := &recordingDispatcher{}
+	pm := NewProcessManager(AreaFulfillment, NewRepository(NewInMemoryEventStore()), dispatcher, NewFulfillment)
+	var started []uuid.UUID
+	HandleProcessEvent(pm, func(_ context.Context, f *Fulfillment, _ *OrderPlaced, _ *ProcessStep) error {
+		started = append(started, f.GetAggregateID())
+		return f.Record("started")
+	}, StartsProcess())
+	correlationID := uuid.New()
+
+	// Act
+	err := pm.Handle(ctx, newTriggerEvent(&OrderPlaced{}, correlationID))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{correlationID}, started)
+	assert.Equal(t, []string{"order_placed"}, pm.Discriminators())
+}
+
+func TestShouldFailWhenTimeoutRequestedWithoutScheduler(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	pm := newFulfillmentManager(NewRepository(NewInMemoryEventStore()), &recordingDispatcher{})
+
+	// Act
+	err := pm.Handle(ctx, newTriggerEvent(&OrderPlaced{OrderID: uuid.New()}, uuid.New()))
+
+	// Assert
+	assert.ErrorContains(t, err, "no TimeoutScheduler")
+}
+
+func TestShouldNotDispatchOrScheduleWhenProcessSaveFails(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewFaultyStore(NewInMemoryEventStore(), FailNthSave(1, ErrConcurrency))
+	scheduler := NewInMemoryTimeoutScheduler(NewFakeClock(0))
+	dispatcher := &recordingDispatcher{}
+	pm := newFulfillmentManager(NewRepository(store), dispatcher, WithTimeoutScheduler(scheduler))
+	placed := newTriggerEvent(&OrderPlaced{OrderID: uuid.New()}, uuid.New())
+
+	// Act
+	failed := pm.Handle(ctx, placed)
+	retried := pm.Handle(ctx, placed)
+
+	// Assert
+	assert.ErrorIs(t, failed, ErrConcurrency)
+	require.NoError(t, retried)
+	assert.Len(t, dispatcher.commands, 1)
+	assert.Len(t, scheduler.pending, 1)
+}
+
+func TestShouldDeriveTimeoutIDsFromTriggeringEvent(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	scheduler := NewInMemoryTimeoutScheduler(NewFakeClock(0))
+	placed := newTriggerEvent(&OrderPlaced{OrderID: uuid.New()}, uuid.New())
+	first := newFulfillmentManager(NewRepository(NewInMemoryEventStore()), &recordingDispatcher{}, WithTimeoutScheduler(scheduler))
+	second := newFulfillmentManager(NewRepository(NewInMemoryEventStore()), &recordingDispatcher{}, WithTimeoutScheduler(scheduler))
+
+	// Act
+	require.NoError(t, first.Handle(ctx, placed))
+	require.NoError(t, second.Handle(ctx, placed))
+
+	// Assert
+	require.Len(t, scheduler.pending, 2)
+	assert.Equal(t, scheduler.pending[0].GetEventID(), scheduler.pending[1].GetEventID())
+	assert.NotEqual(t, uuid.Nil, scheduler.pending[0].GetEventID())
+}
+
+func TestShouldReloadProcessTimeoutFromByteStore(t *testing.T) {
+	// Arrange
+	store := NewInMemoryEventStore(WithStoreCodec(NewJSONCodec()))
+	ctx := context.Background()
+	timeout := &ProcessTimeout{Name: "payment", Due: 100}
+	timeout.SetMetadata(EventMetadata{Entity: NewEntity(uuid.New(), AreaFulfillment), EventID: uuid.New(), Sequence: 1})
+	require.NoError(t, store.SaveEvents(ctx, timeout.GetEntity(), []DomainEvent{timeout}, 0))
+
+	// Act
+	loaded, err := store.LoadEvents(ctx, timeout.GetEntity(), 0)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []DomainEvent{timeout}, loaded)
+}
@@ -0,0 +1,103 @@
+package es
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	errRegisterEventConflict = "RegisterEvent: discriminator %s is already registered to %s"
+	errRegisterEventEmpty    = "RegisterEvent: event %s has an empty discriminator"
+)
+
+var eventRegistry = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+}{types: make(map[string]reflect.Type)}
+
+// EventRegistration describes an event type registered with RegisterEvent.
+type EventRegistration struct {
+	Discriminator string
+	// Type is the registered type, usually a pointer to a struct.
+	Type reflect.Type
+	// Areas is the event's GetAreas (or GetSpaces) list.
+	Areas []string
+}
+
+// New returns a fresh, zero-valued instance of the registered event type.
+func (r EventRegistration) New() DomainEvent {
+	if r.Type.Kind() == reflect.Pointer {
+		return reflect.New(r.Type.Elem()).Interface().(DomainEvent)
+	}
+	return reflect.New(r.Type).Elem().Interface().(DomainEvent)
+}
+
+// RegisterEvent registers an event type by its discriminator so envelopes,
+// codecs, and store decorators can rebuild it. Register every event type an
+// application persists, typically from an init function.
+// Registering the same type twice is a no-op; registering a different type under
+// an existing discriminator panics, like other wiring mistakes.
+func RegisterEvent[T DomainEvent]() {
+	event := newEventInstance[T]()
+	discriminator := event.GetDiscriminator()
+	eventType := reflect.TypeFor[T]()
+	if discriminator == "" {
+		panic(fmt.Sprintf(errRegisterEventEmpty, eventType))
+	}
+
+	eventRegistry.Lock()
+	defer eventRegistry.Unlock()
+
+	if existing, ok := eventRegistry.types[discriminator]; ok {
+		if existing != eventType {
+			panic(fmt.Sprintf(errRegisterEventConflict, discriminator, existing))
+		}
+		return
+	}
+	eventRegistry.types[discriminator] = eventType
+}
+
+// LookupEvent returns the registration for a discriminator.
+func LookupEvent(discriminator string) (EventRegistration, bool) {
+	eventRegistry.RLock()
+	eventType, ok := eventRegistry.types[discriminator]
+	eventRegistry.RUnlock()
+	if !ok {
+		return EventRegistration{}, false
+	}
+	return newEventRegistration(discriminator, eventType), true
+}
+
+// RegisteredEvents returns every registered event type ordered by discriminator.
+func RegisteredEvents() []EventRegistration {
+	eventRegistry.RLock()
+	registrations := make([]EventRegistration, 0, len(eventRegistry.types))
+	for discriminator, eventType := range eventRegistry.types {
+		registrations = append(registrations, newEventRegistration(discriminator, eventType))
+	}
+	eventRegistry.RUnlock()
+
+	slices.SortFunc(registrations, func(a, b EventRegistration) int {
+		return strings.Compare(a.Discriminator, b.Discriminator)
+	})
+	return registrations
+}
+
+// NewEvent returns a fresh instance of the event type registered for discriminator.
+// It returns an error matching ErrUnknownEvent when nothing is registered.
+func NewEvent(discriminator string) (DomainEvent, error) {
+	registration, ok := LookupEvent(discriminator)
+	if !ok {
+		return nil, wrapSentinelError(fmt.Sprintf("event %q is not registered", discriminator), ErrUnknownEvent)
+	}
+	return registration.New(), nil
+}
+
+func newEventRegistration(discriminator string, eventType reflect.Type) EventRegistration {
+	registration := EventRegistration{Discriminator: discriminator, Type: eventType}
+	registration.Areas = eventAreas(registration.New())
+	return registration
+}
@@ -0,0 +1,63 @@
+package es
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	RegisterEvent[*DummyCreated]()
+	RegisterEvent[*DummyAuditLogged]()
+}
+
+type conflictingDummyCreated struct {
+	DomainEventBase
+}
+
+func (e *conflictingDummyCreated) GetDiscriminator() string { return "dummy_created" }
+func (e *conflictingDummyCreated) GetAreas() []string       { return []string{AreaDummy} }
+func (e *conflictingDummyCreated) GetSpaces() []string      { return e.GetAreas() }
+
+func TestShouldLookupRegisteredEvent(t *testing.T) {
+	// Act
+	registration, ok := LookupEvent("dummy_created")
+
+	// Assert
+	require.True(t, ok)
+	assert.Equal(t, []string{AreaTest, AreaDummy}, registration.Areas)
+	assert.IsType(t, &DummyCreated{}, registration.New())
+}
+
+func TestShouldIgnoreDuplicateEventRegistration(t *testing.T) {
+	// Act & Assert
+	assert.NotPanics(t, RegisterEvent[*DummyCreated])
+}
+
+func TestShouldPanicWhenDiscriminatorRegisteredToAnotherType(t *testing.T) {
+	// Act & Assert
+	assert.Panics(t, RegisterEvent[*conflictingDummyCreated])
+}
+
+func TestShouldReturnUnknownEventForUnregisteredDiscriminator(t *testing.T) {
+	// Act
+	_, err := NewEvent("not_registered")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
+
+func TestShouldListRegisteredEventsByDiscriminator(t *testing.T) {
+	// Act
+	registrations := RegisteredEvents()
+
+	// Assert
+	var discriminators []string
+	for _, registration := range registrations {
+		discriminators = append(discriminators, registration.Discriminator)
+	}
+	assert.IsNonDecreasing(t, discriminators)
+	assert.Contains(t, discriminators, "dummy_audit_logged")
+	assert.Contains(t, discriminators, "dummy_created")
+}
@@ -17,6 +17,22 @@ type Repository interface {
 	// Save persists uncommitted domain events and pending audit events.
 	// Audit streams are written first, then the domain stream.
 	Save(context.Context, Aggregate) error
+
+	// Exists reports whether an entity's stream has any events. It asks the store
+	// for the stream version or metadata when supported instead of reading events.
+	Exists(context.Context, Entity) (bool, error)
+
+	// SoftDelete tombstones an entity's stream. Later Load and Save calls for the
+	// entity fail with ErrStreamDeleted.
+	SoftDelete(context.Context, Entity) error
+
+	// HardDelete removes an entity's stream and any tombstone.
+	HardDelete(context.Context, Entity) error
+
+	// TruncateBefore removes events with a sequence lower than the given sequence
+	// from an entity's stream. Aggregates loaded afterwards replay only the retained
+	// events, so the removed prefix must be covered by a snapshot.
+	TruncateBefore(context.Context, Entity, uint64) error
 }
 
 type repository struct {
@@ -33,16 +49,20 @@ func (r *repository) Load(ctx context.Context, a Aggregate) error {
 	ctx, span := startSpan(ctx, spanRepositoryLoad, entity)
 	defer span.End()
 
-	events, err := r.store.LoadEvents(ctx, entity, 0)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		return err
+	count := 0
+	events := func(yield func(DomainEvent, error) bool) {
+		for event, err := range StreamEvents(ctx, r.store, entity, 0) {
+			if err == nil {
+				count++
+			}
+			if !yield(event, err) {
+				return
+			}
+		}
 	}
 
-	span.SetAttributes(attribute.Int(attributeEventsCount, len(events)))
-
-	err = a.Load(events)
+	err := a.LoadStream(events)
+	span.SetAttributes(attribute.Int(attributeEventsCount, count))
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -94,7 +114,7 @@ func (r *repository) Save(ctx context.Context, a Aggregate) error {
 			events = append(events, pa.Event)
 		}
 
-		err := r.store.SaveEvents(ctxAudit, auditEntity, events, 0)
+		err := r.store.SaveEvents(withAuditWrite(ctxAudit), auditEntity, events, 0)
 		if err != nil {
 			spanAudit.RecordError(err)
 			spanAudit.SetStatus(codes.Error, err.Error())
@@ -144,3 +164,58 @@ func groupPendingAuditsByStream(pending []PendingAudit) []auditStreamBatch {
 	}
 	return batches
 }
+
+func (r *repository) Exists(ctx context.Context, entity Entity) (bool, error) {
+	ctx, span := startSpan(ctx, spanRepositoryExists, entity)
+	defer span.End()
+
+	version, exists, err := StreamVersion(ctx, r.store, entity)
+	if err != nil {
+		return false, recordSpanError(span, err)
+	}
+	span.SetAttributes(attribute.String(attributeSequenceCurrent, strconv.FormatUint(version, 10)))
+	return exists, nil
+}
+
+func (r *repository) SoftDelete(ctx context.Context, entity Entity) error {
+	ctx, span := startSpan(ctx, spanRepositorySoftDelete, entity)
+	defer span.End()
+
+	deleter, err := r.streamDeleter()
+	if err == nil {
+		err = deleter.SoftDeleteStream(ctx, entity)
+	}
+	return recordSpanError(span, err)
+}
+
+func (r *repository) HardDelete(ctx context.Context, entity Entity) error {
+	ctx, span := startSpan(ctx, spanRepositoryHardDelete, entity)
+	defer span.End()
+
+	deleter, err := r.streamDeleter()
+	if err == nil {
+		err = deleter.HardDeleteStream(ctx, entity)
+	}
+	return recordSpanError(span, err)
+}
+
+func (r *repository) TruncateBefore(ctx context.Context, entity Entity, sequence uint64) error {
+	ctx, span := startSpan(ctx, spanRepositoryTruncate, entity,
+		attribute.String(attributeSequenceExpected, strconv.FormatUint(sequence, 10)),
+	)
+	defer span.End()
+
+	deleter, err := r.streamDeleter()
+	if err == nil {
+		err = deleter.TruncateStreamBefore(ctx, entity, sequence)
+	}
+	return recordSpanError(span, err)
+}
+
+func (r *repository) streamDeleter() (StreamDeleter, error) {
+	deleter, ok := r.store.(StreamDeleter)
+	if !ok {
+		return nil, unsupportedError(r.store, "stream deletion")
+	}
+	return deleter, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/codes"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
@@ -382,6 +383,161 @@ func TestShouldCreateSpanWhenSaveIsNoOp(t *testing.T) {
 	mockStore.AssertNotCalled(t, "SaveEvents")
 }
 
+func TestShouldLoadAggregateFromStreamingStore(t *testing.T) {
+	// Arrange
+	spanRecorder := setupSpanRecorder(t)
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	repo := NewRepository(store)
+	dummy := NewDummy()
+	require.NoError(t, store.SaveEvents(ctx, dummy.GetEntity(), newDummyEvents(dummy.GetEntity(), 3), 0))
+
+	// Act
+	err := repo.Load(ctx, dummy)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "name-3", dummy.name)
+	assert.Equal(t, uint64(3), dummy.GetCommittedSequence())
+	spans := spanRecorder.Ended()
+	assert.Len(t, spans, 1)
+	assertSpanInt64Attribute(t, spans[0], attributeEventsCount, 3)
+}
+
+func TestShouldReturnStreamDeletedWhenLoadingSoftDeletedAggregate(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	repo := NewRepository(store)
+	dummy := NewDummy()
+	require.NoError(t, dummy.Create("test"))
+	require.NoError(t, repo.Save(ctx, dummy))
+
+	// Act
+	err := repo.SoftDelete(ctx, dummy.GetEntity())
+
+	// Assert
+	require.NoError(t, err)
+	reloaded := &Dummy{Aggregate: NewAggregate(ctx, AreaDummy, dummy.GetAggregateID())}
+	RegisterHandler(reloaded, reloaded.OnDummyCreated)
+	assert.ErrorIs(t, repo.Load(ctx, reloaded), ErrStreamDeleted)
+	require.NoError(t, reloaded.Create("again"))
+	assert.ErrorIs(t, repo.Save(ctx, reloaded), ErrStreamDeleted)
+}
+
+func TestShouldSaveAfterLoadingTruncatedAggregate(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	repo := NewRepository(store)
+	dummy := NewDummy()
+	require.NoError(t, store.SaveEvents(ctx, dummy.GetEntity(), newDummyEvents(dummy.GetEntity(), 4), 0))
+	require.NoError(t, repo.TruncateBefore(ctx, dummy.GetEntity(), 3))
+
+	// Act
+	require.NoError(t, repo.Load(ctx, dummy))
+	require.NoError(t, dummy.Create("after-truncate"))
+	err := repo.Save(ctx, dummy)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, dummy.GetCommittedEvents(), 3)
+	assert.Equal(t, uint64(5), dummy.GetCommittedSequence())
+	assert.Equal(t, uint64(5), dummy.GetCommittedEvents()[2].GetSequence())
+}
+
+func TestShouldTrackCommittedSequenceAcrossInterleavedTruncateLoadAndSave(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	repo := NewRepository(store)
+	first := NewDummy()
+	entity := first.GetEntity()
+	load := func() *Dummy {
+		dummy := &Dummy{Aggregate: NewAggregate(ctx, entity.Area, entity.ID)}
+		RegisterHandler(dummy, dummy.OnDummyCreated)
+		require.NoError(t, repo.Load(ctx, dummy))
+		return dummy
+	}
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 4), 0))
+	require.NoError(t, repo.Load(ctx, first))
+
+	// Act
+	require.NoError(t, repo.TruncateBefore(ctx, entity, 4))
+	require.NoError(t, first.Create("first"))
+	firstErr := repo.Save(ctx, first)
+	require.NoError(t, repo.TruncateBefore(ctx, entity, 10))
+	second := load()
+	require.NoError(t, second.Create("second"))
+	secondErr := repo.Save(ctx, second)
+	require.NoError(t, first.Create("stale"))
+	staleErr := repo.Save(ctx, first)
+	reloaded := load()
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.ErrorIs(t, staleErr, ErrConcurrency)
+	assert.Equal(t, uint64(6), second.GetCommittedSequence())
+	assert.Len(t, second.GetCommittedEvents(), 2)
+	assert.Equal(t, uint64(6), reloaded.GetCommittedSequence())
+	assert.Equal(t, []uint64{5, 6}, sequencesOf(reloaded.GetCommittedEvents()))
+}
+
+func TestShouldReturnUnsupportedWhenStoreCannotDelete(t *testing.T) {
+	// Arrange
+	mockStore := new(MockStore)
+	repo := NewRepository(mockStore)
+	entity := NewDummy().GetEntity()
+
+	// Act
+	softErr := repo.SoftDelete(context.Background(), entity)
+	hardErr := repo.HardDelete(context.Background(), entity)
+	truncateErr := repo.TruncateBefore(context.Background(), entity, 1)
+
+	// Assert
+	assert.ErrorIs(t, softErr, ErrUnsupported)
+	assert.ErrorIs(t, hardErr, ErrUnsupported)
+	assert.ErrorIs(t, truncateErr, ErrUnsupported)
+}
+
+func TestShouldReportExistenceFromStreamMetadata(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	repo := NewRepository(store)
+	dummy := NewDummy()
+	require.NoError(t, store.(StreamMetadataStore).SetStreamSettings(ctx, dummy.GetEntity(), StreamSettings{MaxCount: 10}))
+
+	// Act
+	before, beforeErr := repo.Exists(ctx, dummy.GetEntity())
+	require.NoError(t, dummy.Create("test"))
+	require.NoError(t, repo.Save(ctx, dummy))
+	after, afterErr := repo.Exists(ctx, dummy.GetEntity())
+
+	// Assert
+	assert.NoError(t, beforeErr)
+	assert.NoError(t, afterErr)
+	assert.False(t, before)
+	assert.True(t, after)
+}
+
+func TestShouldReportExistenceByReadingWhenStoreHasNoMetadata(t *testing.T) {
+	// Arrange
+	mockStore := new(MockStore)
+	repo := NewRepository(mockStore)
+	dummy := NewDummy()
+	mockStore.On("LoadEvents", mock.Anything, dummy.GetEntity(), uint64(0)).Return([]DomainEvent{&DummyCreated{Name: "x"}}, nil)
+
+	// Act
+	exists, err := repo.Exists(context.Background(), dummy.GetEntity())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	mockStore.AssertExpectations(t)
+}
+
 func setupSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
 	t.Helper()
 
@@ -0,0 +1,476 @@
+package es
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SchedulerArea is the area of the streams a Scheduler persists its messages in.
+	SchedulerArea = "es-scheduler"
+
+	messageScheduledDiscriminator = "es://message_scheduled"
+	messageDeliveredDiscriminator = "es://message_delivered"
+	messageCancelledDiscriminator = "es://message_cancelled"
+	messageClaimedDiscriminator   = "es://message_claimed"
+
+	// defaultSchedulerLease is how long a claim on a message lasts, in clock units:
+	// one minute for the default clock, which counts Unix milliseconds.
+	defaultSchedulerLease = 60_000
+
+	// schedulerAppendAttempts bounds how often an append is retried after another
+	// scheduler instance appended to the same stream.
+	schedulerAppendAttempts = 3
+)
+
+// RegisterSchedulerEvents registers the events a Scheduler persists. Call it once
+// at startup, alongside RegisterEvent calls for the application's own events,
+// before using a Scheduler. ProcessTimeout is registered by default.
+func RegisterSchedulerEvents() {
+	RegisterEvent[*MessageScheduled]()
+	RegisterEvent[*MessageDelivered]()
+	RegisterEvent[*MessageCancelled]()
+	RegisterEvent[*MessageClaimed]()
+}
+
+// MessageScheduled records a message persisted by a Scheduler. Message is the
+// envelope of the event to deliver, including its target entity, event ID, and
+// correlation and causation IDs.
+type MessageScheduled struct {
+	DomainEventBase
+	Due     int64    `json:"due"`
+	Message Envelope `json:"message"`
+}
+
+// GetDiscriminator implements polymorphic.Polymorphic.
+func (e *MessageScheduled) GetDiscriminator() string { return messageScheduledDiscriminator }
+
+// GetAreas returns SchedulerArea.
+func (e *MessageScheduled) GetAreas() []string { return []string{SchedulerArea} }
+
+// GetSpaces returns the same values as GetAreas.
+func (e *MessageScheduled) GetSpaces() []string { return e.GetAreas() }
+
+// MessageDelivered records that a scheduled message was handled successfully.
+type MessageDelivered struct {
+	DomainEventBase
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+// GetDiscriminator implements polymorphic.Polymorphic.
+func (e *MessageDelivered) GetDiscriminator() string { return messageDeliveredDiscriminator }
+
+// GetAreas returns SchedulerArea.
+func (e *MessageDelivered) GetAreas() []string { return []string{SchedulerArea} }
+
+// GetSpaces returns the same values as GetAreas.
+func (e *MessageDelivered) GetSpaces() []string { return e.GetAreas() }
+
+// MessageCancelled records that a scheduled message was cancelled before delivery.
+type MessageCancelled struct {
+	DomainEventBase
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+// GetDiscriminator implements polymorphic.Polymorphic.
+func (e *MessageCancelled) GetDiscriminator() string { return messageCancelledDiscriminator }
+
+// GetAreas returns SchedulerArea.
+func (e *MessageCancelled) GetAreas() []string { return []string{SchedulerArea} }
+
+// GetSpaces returns the same values as GetAreas.
+func (e *MessageCancelled) GetSpaces() []string { return e.GetAreas() }
+
+// MessageClaimed records that a scheduler instance is delivering a message. Other
+// instances leave the message alone until Until, a timestamp of the scheduler clock.
+type MessageClaimed struct {
+	DomainEventBase
+	MessageID uuid.UUID `json:"message_id"`
+	Owner     uuid.UUID `json:"owner"`
+	Until     int64     `json:"until"`
+}
+
+// GetDiscriminator implements polymorphic.Polymorphic.
+func (e *MessageClaimed) GetDiscriminator() string { return messageClaimedDiscriminator }
+
+// GetAreas returns SchedulerArea.
+func (e *MessageClaimed) GetAreas() []string { return []string{SchedulerArea} }
+
+// GetSpaces returns the same values as GetAreas.
+func (e *MessageClaimed) GetSpaces() []string { return e.GetAreas() }
+
+// ScheduledMessage is a pending message of a Scheduler.
+type ScheduledMessage struct {
+	ID  uuid.UUID
+	Due int64
+	// Target is the entity the message is addressed to.
+	Target        Entity
+	Discriminator string
+	CorrelationID uuid.UUID
+	CausationID   uuid.UUID
+
+	envelope     Envelope
+	sequence     uint64
+	claimedBy    uuid.UUID
+	claimedUntil int64
+}
+
+// SchedulerOption configures a Scheduler.
+type SchedulerOption func(*Scheduler)
+
+// WithSchedulerClock sets the clock used to decide which messages are due. Pass a
+// FakeClock in tests to fire messages deterministically.
+func WithSchedulerClock(clock Clock) SchedulerOption {
+	return func(s *Scheduler) {
+		s.clock = clock
+	}
+}
+
+// WithSchedulerIDGenerator sets the generator for message and record event IDs.
+func WithSchedulerIDGenerator(ids IDGenerator) SchedulerOption {
+	return func(s *Scheduler) {
+		s.ids = ids
+	}
+}
+
+// WithSchedulerLease sets how long, in clock units, a scheduler instance holds its
+// claim on a message it is delivering. Another instance delivers the message again
+// once the lease expires without a delivery being recorded, so the lease should
+// exceed the longest expected handler run.
+func WithSchedulerLease(lease int64) SchedulerOption {
+	return func(s *Scheduler) {
+		if lease > 0 {
+			s.lease = lease
+		}
+	}
+}
+
+// WithSchedulerName stores messages in the scheduler stream for name instead of
+// the default one, to run independent schedulers on one store.
+func WithSchedulerName(name string) SchedulerOption {
+	return func(s *Scheduler) {
+		s.stream = schedulerStream(name)
+	}
+}
+
+// Scheduler persists deferred messages in a stream of its store and delivers each
+// one to a handler once it is due. Pending messages are rebuilt from the stream, so
+// they survive restarts, and several Scheduler instances may share a stream: an
+// instance claims a message before handling it, and the others skip it until the
+// claim's lease expires. Messages are decoded from their envelopes when delivered,
+// so their event types must be registered, along with RegisterSchedulerEvents.
+//
+// A message is recorded as delivered only after its handler succeeds. A crash in
+// between delivers it again once the lease expires, so handlers should be
+// idempotent; ProcessManager.Handle is. Scheduler implements TimeoutScheduler for
+// process managers.
+//
+// On stores that implement StreamDeleter, the stream is truncated before its oldest
+// pending message after deliveries and cancellations, so it does not grow with
+// every message ever scheduled. Other stores keep the full history.
+type Scheduler struct {
+	store  Store
+	stream Entity
+	clock  Clock
+	ids    IDGenerator
+	lease  int64
+	owner  uuid.UUID
+
+	fireMu  sync.Mutex
+	mu      sync.Mutex
+	head    uint64
+	pending map[uuid.UUID]ScheduledMessage
+}
+
+// NewScheduler creates a scheduler that persists its messages in store.
+func NewScheduler(store Store, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		store:   store,
+		stream:  schedulerStream(""),
+		clock:   systemClock{},
+		ids:     randomIDGenerator{},
+		lease:   defaultSchedulerLease,
+		pending: make(map[uuid.UUID]ScheduledMessage),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.owner = s.ids.NewID()
+	return s
+}
+
+// schedulerStream returns the stream of the scheduler with the given name.
+func schedulerStream(name string) Entity {
+	return NewEntity(uuid.NewSHA1(uuid.NameSpaceURL, []byte("es://scheduler/"+name)), SchedulerArea)
+}
+
+// Schedule persists message for delivery at due, a timestamp of the scheduler's
+// clock, and returns its ID. The message metadata is stamped with target, a new
+// event ID, and the correlation and causation IDs from ctx.
+func (s *Scheduler) Schedule(ctx context.Context, due int64, target Entity, message DomainEvent) (uuid.UUID, error) {
+	id := s.ids.NewID()
+	message.SetMetadata(EventMetadata{
+		Entity:        target,
+		EventID:       id,
+		CorrelationID: GetCorrelationID(ctx),
+		CausationID:   GetCausationID(ctx),
+		Timestamp:     s.clock.GetTimestamp(),
+	})
+	if err := s.schedule(ctx, due, message); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// ScheduleAfter schedules message for delivery delay clock units from now.
+func (s *Scheduler) ScheduleAfter(ctx context.Context, delay int64, target Entity, message DomainEvent) (uuid.UUID, error) {
+	return s.Schedule(ctx, s.clock.GetTimestamp()+delay, target, message)
+}
+
+// ScheduleTimeout implements TimeoutScheduler. The timeout keeps the metadata set
+// by the process manager and is due at timeout.Due.
+func (s *Scheduler) ScheduleTimeout(ctx context.Context, timeout *ProcessTimeout) error {
+	return s.schedule(ctx, timeout.Due, timeout)
+}
+
+func (s *Scheduler) schedule(ctx context.Context, due int64, message DomainEvent) error {
+	envelope, err := NewEnvelope(message)
+	if err != nil {
+		return err
+	}
+	return s.append(ctx, func() (DomainEvent, bool) {
+		if _, exists := s.pending[envelope.Metadata.EventID]; exists {
+			return nil, false
+		}
+		return &MessageScheduled{Due: due, Message: envelope}, true
+	})
+}
+
+// Cancel removes a pending message. It reports false when the message is unknown
+// or was already delivered or cancelled.
+func (s *Scheduler) Cancel(ctx context.Context, id uuid.UUID) (bool, error) {
+	cancelled := false
+	err := s.append(ctx, func() (DomainEvent, bool) {
+		_, cancelled = s.pending[id]
+		return &MessageCancelled{MessageID: id}, cancelled
+	})
+	if err != nil || !cancelled {
+		return false, err
+	}
+	return true, s.compact(ctx)
+}
+
+// Pending returns the pending messages ordered by due time.
+func (s *Scheduler) Pending(ctx context.Context) ([]ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.catchUp(ctx); err != nil {
+		return nil, err
+	}
+	return s.sortedPending(), nil
+}
+
+// FireDue delivers the messages that are due, earliest first, to handle with the
+// message's correlation and causation IDs in the context. Each message is claimed
+// before it is handled and recorded as delivered before the next one is claimed;
+// messages claimed by another instance whose lease has not expired are skipped.
+// FireDue stops at the first error and returns the number of messages delivered.
+func (s *Scheduler) FireDue(ctx context.Context, handle func(context.Context, DomainEvent) error) (int, error) {
+	s.fireMu.Lock()
+	defer s.fireMu.Unlock()
+
+	s.mu.Lock()
+	err := s.catchUp(ctx)
+	pending := s.sortedPending()
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.clock.GetTimestamp()
+	fired := 0
+	for _, message := range pending {
+		if message.Due > now {
+			break
+		}
+		claimed, err := s.claim(ctx, message.ID)
+		if err != nil {
+			return fired, err
+		}
+		if !claimed {
+			continue
+		}
+		event, err := message.envelope.Event()
+		if err != nil {
+			return fired, fmt.Errorf("scheduled message %s: %w", message.ID, err)
+		}
+		if err := handle(ContextWithTracing(ctx, message.CorrelationID, message.CausationID), event); err != nil {
+			return fired, err
+		}
+		err = s.append(ctx, func() (DomainEvent, bool) {
+			if _, exists := s.pending[message.ID]; !exists {
+				return nil, false
+			}
+			return &MessageDelivered{MessageID: message.ID}, true
+		})
+		if err != nil {
+			return fired, err
+		}
+		fired++
+	}
+	if fired == 0 {
+		return 0, nil
+	}
+	return fired, s.compact(ctx)
+}
+
+// claim records that this instance is delivering a message. It reports false when
+// the message is no longer pending or another instance holds an unexpired claim.
+func (s *Scheduler) claim(ctx context.Context, id uuid.UUID) (bool, error) {
+	claimed := false
+	err := s.append(ctx, func() (DomainEvent, bool) {
+		now := s.clock.GetTimestamp()
+		message, exists := s.pending[id]
+		claimed = exists && (message.claimedBy == s.owner || message.claimedUntil <= now)
+		return &MessageClaimed{MessageID: id, Owner: s.owner, Until: now + s.lease}, claimed
+	})
+	return claimed && err == nil, err
+}
+
+// compact truncates the stream before the oldest pending message, when the store
+// supports it. A stale view of the stream only makes it truncate less.
+func (s *Scheduler) compact(ctx context.Context) error {
+	deleter, ok := s.store.(StreamDeleter)
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	before := s.head + 1
+	for _, message := range s.pending {
+		before = min(before, message.sequence)
+	}
+	s.mu.Unlock()
+
+	if err := deleter.TruncateStreamBefore(ctx, s.stream, before); err != nil && !errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// Run calls FireDue with handle every interval until ctx is done, and returns the
+// first FireDue error or the context's error.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration, handle func(context.Context, DomainEvent) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.FireDue(ctx, handle); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// append records the event built by next, reloading the stream and building the
+// event again when another scheduler appended first. next runs with s.mu held and
+// returns false when there is nothing to record.
+func (s *Scheduler) append(ctx context.Context, next func() (DomainEvent, bool)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for range schedulerAppendAttempts {
+		if err = s.catchUp(ctx); err != nil {
+			return err
+		}
+		event, ok := next()
+		if !ok {
+			return nil
+		}
+		event.SetMetadata(EventMetadata{
+			Entity:        s.stream,
+			EventID:       s.ids.NewID(),
+			CorrelationID: GetCorrelationID(ctx),
+			CausationID:   GetCausationID(ctx),
+			Timestamp:     s.clock.GetTimestamp(),
+			Sequence:      s.head + 1,
+		})
+		err = s.store.SaveEvents(ctx, s.stream, []DomainEvent{event}, s.head)
+		if err == nil {
+			s.apply(event)
+			return nil
+		}
+		if !errors.Is(err, ErrConcurrency) {
+			return err
+		}
+	}
+	return err
+}
+
+// catchUp applies the scheduler stream events appended since the last call. When
+// another instance truncated events this one has not applied yet, the pending
+// messages are rebuilt from the events that remain. It must be called with s.mu held.
+func (s *Scheduler) catchUp(ctx context.Context) error {
+	for event, err := range StreamEvents(ctx, s.store, s.stream, s.head+1) {
+		if err != nil {
+			return err
+		}
+		if s.head > 0 && event.GetSequence() > s.head+1 {
+			s.head = 0
+			clear(s.pending)
+			return s.catchUp(ctx)
+		}
+		s.apply(event)
+	}
+	return nil
+}
+
+func (s *Scheduler) apply(event DomainEvent) {
+	switch e := event.(type) {
+	case *MessageScheduled:
+		metadata := e.Message.Metadata
+		s.pending[metadata.EventID] = ScheduledMessage{
+			ID:            metadata.EventID,
+			Due:           e.Due,
+			Target:        metadata.Entity,
+			Discriminator: e.Message.Discriminator,
+			CorrelationID: metadata.CorrelationID,
+			CausationID:   metadata.CausationID,
+			envelope:      e.Message,
+			sequence:      e.GetSequence(),
+		}
+	case *MessageClaimed:
+		if message, exists := s.pending[e.MessageID]; exists {
+			message.claimedBy = e.Owner
+			message.claimedUntil = e.Until
+			s.pending[e.MessageID] = message
+		}
+	case *MessageDelivered:
+		delete(s.pending, e.MessageID)
+	case *MessageCancelled:
+		delete(s.pending, e.MessageID)
+	}
+	s.head = max(s.head, event.GetSequence())
+}
+
+func (s *Scheduler) sortedPending() []ScheduledMessage {
+	pending := make([]ScheduledMessage, 0, len(s.pending))
+	for _, message := range s.pending {
+		pending = append(pending, message)
+	}
+	slices.SortFunc(pending, func(a, b ScheduledMessage) int {
+		return cmp.Or(cmp.Compare(a.Due, b.Due), cmp.Compare(a.sequence, b.sequence))
+	})
+	return pending
+}
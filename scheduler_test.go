@@ -0,0 +1,233 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	RegisterSchedulerEvents()
+	RegisterEvent[*OrderPlaced]()
+}
+
+type deliveredMessage struct {
+	event         DomainEvent
+	correlationID uuid.UUID
+	causationID   uuid.UUID
+}
+
+func collectMessages(delivered *[]deliveredMessage) func(context.Context, DomainEvent) error {
+	return func(ctx context.Context, event DomainEvent) error {
+		*delivered = append(*delivered, deliveredMessage{
+			event:         event,
+			correlationID: GetCorrelationID(ctx),
+			causationID:   GetCausationID(ctx),
+		})
+		return nil
+	}
+}
+
+func TestShouldDeliverScheduledMessageWhenDue(t *testing.T) {
+	// Arrange
+	clock := NewFakeClock(1000)
+	scheduler := NewScheduler(NewInMemoryEventStore(), WithSchedulerClock(clock))
+	correlationID, causationID := uuid.New(), uuid.New()
+	ctx := ContextWithTracing(context.Background(), correlationID, causationID)
+	target := NewEntity(uuid.New(), AreaDummy)
+	orderID := uuid.New()
+	id, err := scheduler.ScheduleAfter(ctx, 50, target, &OrderPlaced{OrderID: orderID})
+	require.NoError(t, err)
+	var delivered []deliveredMessage
+
+	// Act
+	early, earlyErr := scheduler.FireDue(context.Background(), collectMessages(&delivered))
+	clock.Advance(50)
+	due, dueErr := scheduler.FireDue(context.Background(), collectMessages(&delivered))
+
+	// Assert
+	require.NoError(t, earlyErr)
+	require.NoError(t, dueErr)
+	assert.Equal(t, 0, early)
+	assert.Equal(t, 1, due)
+	require.Len(t, delivered, 1)
+	message := delivered[0]
+	assert.Equal(t, orderID, message.event.(*OrderPlaced).OrderID)
+	assert.Equal(t, id, message.event.GetEventID())
+	assert.Equal(t, target, message.event.GetEntity())
+	assert.Equal(t, correlationID, message.correlationID)
+	assert.Equal(t, causationID, message.causationID)
+	pending, err := scheduler.Pending(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestShouldRestoreScheduledMessagesAfterRestart(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore(WithStoreCodec(NewJSONCodec()))
+	clock := NewFakeClock(0)
+	first := NewScheduler(store, WithSchedulerClock(clock))
+	target := NewEntity(uuid.New(), AreaDummy)
+	_, err := first.Schedule(ctx, 10, target, &OrderPlaced{})
+	require.NoError(t, err)
+	_, err = first.Schedule(ctx, 5, target, &OrderPlaced{})
+	require.NoError(t, err)
+	clock.Set(20)
+	var delivered []deliveredMessage
+
+	// Act
+	restarted := NewScheduler(store, WithSchedulerClock(clock))
+	fired, fireErr := restarted.FireDue(ctx, collectMessages(&delivered))
+	pending, pendingErr := first.Pending(ctx)
+
+	// Assert
+	require.NoError(t, fireErr)
+	require.NoError(t, pendingErr)
+	assert.Equal(t, 2, fired)
+	assert.Empty(t, pending)
+	require.Len(t, delivered, 2)
+	assert.Equal(t, target, delivered[0].event.GetEntity())
+}
+
+func TestShouldNotDeliverCancelledMessages(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	clock := NewFakeClock(0)
+	scheduler := NewScheduler(NewInMemoryEventStore(), WithSchedulerClock(clock))
+	id, err := scheduler.Schedule(ctx, 10, NewEntity(uuid.New(), AreaDummy), &OrderPlaced{})
+	require.NoError(t, err)
+	clock.Set(10)
+	var delivered []deliveredMessage
+
+	// Act
+	cancelled, cancelErr := scheduler.Cancel(ctx, id)
+	again, againErr := scheduler.Cancel(ctx, id)
+	fired, fireErr := scheduler.FireDue(ctx, collectMessages(&delivered))
+
+	// Assert
+	require.NoError(t, cancelErr)
+	require.NoError(t, againErr)
+	require.NoError(t, fireErr)
+	assert.True(t, cancelled)
+	assert.False(t, again)
+	assert.Equal(t, 0, fired)
+}
+
+func TestShouldKeepMessagePendingWhenHandlerFails(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	clock := NewFakeClock(0)
+	scheduler := NewScheduler(NewInMemoryEventStore(), WithSchedulerClock(clock))
+	_, err := scheduler.Schedule(ctx, 0, NewEntity(uuid.New(), AreaDummy), &OrderPlaced{})
+	require.NoError(t, err)
+	handlerErr := errors.New("handler failed")
+
+	// Act
+	fired, fireErr := scheduler.FireDue(ctx, func(context.Context, DomainEvent) error { return handlerErr })
+
+	// Assert
+	assert.ErrorIs(t, fireErr, handlerErr)
+	assert.Equal(t, 0, fired)
+	pending, err := scheduler.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
+
+func TestShouldSkipMessagesClaimedByAnotherScheduler(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	clock := NewFakeClock(0)
+	first := NewScheduler(store, WithSchedulerClock(clock), WithSchedulerLease(30))
+	second := NewScheduler(store, WithSchedulerClock(clock), WithSchedulerLease(30))
+	_, err := first.Schedule(ctx, 0, NewEntity(uuid.New(), AreaDummy), &OrderPlaced{})
+	require.NoError(t, err)
+	var delivered []deliveredMessage
+	handlerErr := errors.New("handler failed")
+
+	// Act
+	var concurrent int
+	_, firstErr := first.FireDue(ctx, func(context.Context, DomainEvent) error {
+		concurrent, err = second.FireDue(ctx, collectMessages(&delivered))
+		require.NoError(t, err)
+		return handlerErr
+	})
+	beforeExpiry, beforeErr := second.FireDue(ctx, collectMessages(&delivered))
+	clock.Advance(30)
+	afterExpiry, afterErr := second.FireDue(ctx, collectMessages(&delivered))
+
+	// Assert
+	assert.ErrorIs(t, firstErr, handlerErr)
+	require.NoError(t, beforeErr)
+	require.NoError(t, afterErr)
+	assert.Equal(t, 0, concurrent)
+	assert.Equal(t, 0, beforeExpiry)
+	assert.Equal(t, 1, afterExpiry)
+	assert.Len(t, delivered, 1)
+}
+
+func TestShouldTruncateSchedulerStreamBeforeOldestPendingMessage(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	clock := NewFakeClock(0)
+	scheduler := NewScheduler(store, WithSchedulerClock(clock))
+	lagging := NewScheduler(store, WithSchedulerClock(clock))
+	target := NewEntity(uuid.New(), AreaDummy)
+	for due := range int64(3) {
+		_, err := scheduler.Schedule(ctx, due*10, target, &OrderPlaced{})
+		require.NoError(t, err)
+	}
+	_, err := lagging.Pending(ctx)
+	require.NoError(t, err)
+	deliver := func(context.Context, DomainEvent) error { return nil }
+	clock.Set(10)
+
+	// Act
+	partial, partialErr := scheduler.FireDue(ctx, deliver)
+	partialEvents, _ := store.LoadEvents(ctx, schedulerStream(""), 0)
+	clock.Set(20)
+	rest, restErr := scheduler.FireDue(ctx, deliver)
+	restEvents, _ := store.LoadEvents(ctx, schedulerStream(""), 0)
+
+	// Assert
+	require.NoError(t, partialErr)
+	require.NoError(t, restErr)
+	assert.Equal(t, 2, partial)
+	assert.Equal(t, 1, rest)
+	require.NotEmpty(t, partialEvents)
+	assert.Equal(t, uint64(3), partialEvents[0].GetSequence())
+	assert.Len(t, restEvents, 1)
+	_, err = scheduler.Schedule(ctx, 30, target, &OrderPlaced{})
+	require.NoError(t, err)
+	pending, err := lagging.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, int64(30), pending[0].Due)
+}
+
+func TestShouldFireProcessTimeoutsFromPersistedScheduler(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore()
+	clock := NewFakeClock(0)
+	dispatcher := &recordingDispatcher{}
+	pm := newFulfillmentManager(NewRepository(store), dispatcher,
+		WithProcessClock(clock), WithTimeoutScheduler(NewScheduler(store, WithSchedulerClock(clock))))
+	orderID := uuid.New()
+	require.NoError(t, pm.Handle(ctx, newTriggerEvent(&OrderPlaced{OrderID: orderID}, uuid.New())))
+	clock.Advance(100)
+
+	// Act
+	fired, err := NewScheduler(store, WithSchedulerClock(clock)).FireDue(ctx, pm.Handle)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, fired)
+	assert.Equal(t, []any{RequestPayment{OrderID: orderID}, CancelOrder{OrderID: orderID}}, dispatcher.commands)
+}
@@ -0,0 +1,399 @@
+package es
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RedactedValue replaces personal string fields whose subject has been forgotten.
+	RedactedValue = "[redacted]"
+
+	// personalTag marks an event field as personal data: `es:"personal"`.
+	personalTag = "personal"
+	// subjectTag marks the event field holding the data subject's ID: `es:"subject"`.
+	subjectTag = "subject"
+
+	// shreddedPrefix marks a personal field value sealed by ShreddingStore.
+	shreddedPrefix = "es:pii:v1:"
+)
+
+// SubjectKeyStore holds one encryption key per data subject for ShreddingStore.
+// Destroying a subject's key makes every field sealed under it unreadable.
+type SubjectKeyStore interface {
+	// SubjectKey returns the subject's key, creating one when create is set and none
+	// exists. It returns an error matching ErrKeyNotFound when the key does not exist
+	// and ErrSubjectForgotten when the subject has been forgotten.
+	SubjectKey(ctx context.Context, subjectID string, create bool) ([]byte, error)
+
+	// ForgetSubject destroys the subject's key. It is idempotent.
+	ForgetSubject(ctx context.Context, subjectID string) error
+}
+
+// ShreddingStore encrypts event fields tagged `es:"personal"` with a key per data
+// subject, so personal data can be erased from immutable streams by destroying the
+// key. The subject is read from the field tagged `es:"subject"` (a uuid.UUID,
+// string, or fmt.Stringer) and defaults to the event's entity ID.
+// Personal fields must be strings or byte slices. Once a subject is forgotten, its
+// string fields read as RedactedValue and its byte fields as nil on every read path.
+// A subject key that is missing without having been forgotten fails reads with
+// ErrKeyNotFound instead.
+type ShreddingStore struct {
+	ForwardingStore
+	keys SubjectKeyStore
+}
+
+// NewShreddingStore wraps store with field-level encryption of personal data.
+func NewShreddingStore(store Store, keys SubjectKeyStore) *ShreddingStore {
+	return &ShreddingStore{ForwardingStore: ForwardingStore{Next: store}, keys: keys}
+}
+
+// Shredding returns a StoreMiddleware that wraps a store in a ShreddingStore.
+func Shredding(keys SubjectKeyStore) StoreMiddleware {
+	return func(next Store) Store {
+		return NewShreddingStore(next, keys)
+	}
+}
+
+// ForgetSubject destroys the subject's key. Personal fields written for the subject
+// are redacted on every later read.
+func (s *ShreddingStore) ForgetSubject(ctx context.Context, subjectID string) error {
+	return s.keys.ForgetSubject(ctx, subjectID)
+}
+
+// SaveEvents implements Store.SaveEvents. Events are copied before their personal
+// fields are sealed, so the caller's events keep their plaintext values.
+func (s *ShreddingStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
+	shredded, err := s.shredAll(ctx, events)
+	if err != nil {
+		return err
+	}
+	return s.Next.SaveEvents(ctx, entity, shredded, expectedSequence)
+}
+
+// CopyEvents implements StreamCopier.CopyEvents, sealing personal fields as SaveEvents does.
+func (s *ShreddingStore) CopyEvents(ctx context.Context, entity Entity, events []DomainEvent, startSequence uint64) error {
+	shredded, err := s.shredAll(ctx, events)
+	if err != nil {
+		return err
+	}
+	return CopyEvents(ctx, s.Next, entity, shredded, startSequence)
+}
+
+func (s *ShreddingStore) shredAll(ctx context.Context, events []DomainEvent) ([]DomainEvent, error) {
+	shredded := make([]DomainEvent, 0, len(events))
+	for _, event := range events {
+		event, err := s.shred(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		shredded = append(shredded, event)
+	}
+	return shredded, nil
+}
+
+// LoadEvents implements Store.LoadEvents.
+func (s *ShreddingStore) LoadEvents(ctx context.Context, entity Entity, minSequence uint64) ([]DomainEvent, error) {
+	events, err := s.Next.LoadEvents(ctx, entity, minSequence)
+	if err != nil {
+		return nil, err
+	}
+	return s.restoreAll(ctx, events)
+}
+
+// LoadEventStream implements StreamingStore.LoadEventStream.
+func (s *ShreddingStore) LoadEventStream(ctx context.Context, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error] {
+	return func(yield func(DomainEvent, error) bool) {
+		for event, err := range StreamEvents(ctx, s.Next, entity, minSequence) {
+			if err == nil {
+				event, err = s.restore(ctx, event)
+			}
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ReadStream implements StreamReader.ReadStream.
+func (s *ShreddingStore) ReadStream(ctx context.Context, entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	events, err := ReadStream(ctx, s.Next, entity, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.restoreAll(ctx, events)
+}
+
+func (s *ShreddingStore) restoreAll(ctx context.Context, events []DomainEvent) ([]DomainEvent, error) {
+	restored := make([]DomainEvent, 0, len(events))
+	for _, event := range events {
+		event, err := s.restore(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		restored = append(restored, event)
+	}
+	return restored, nil
+}
+
+func (s *ShreddingStore) shred(ctx context.Context, event DomainEvent) (DomainEvent, error) {
+	fields, err := personalFieldsOf(event)
+	if err != nil || fields == nil {
+		return event, err
+	}
+
+	clone, value := cloneEventValue(event)
+	subjectID := fields.subjectID(value, event.GetMetadata())
+	key, err := s.keys.SubjectKey(ctx, subjectID, true)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID := event.GetMetadata().EventID
+	for _, field := range fields.personal {
+		target := value.Field(field.index)
+		plaintext := fieldBytes(target)
+		if len(plaintext) == 0 {
+			continue
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		sealed := aead.Seal(nonce, nonce, plaintext, shreddedAdditionalData(eventID, field.name))
+		setFieldBytes(target, []byte(shreddedPrefix+base64.StdEncoding.EncodeToString(sealed)))
+	}
+	return clone, nil
+}
+
+func (s *ShreddingStore) restore(ctx context.Context, event DomainEvent) (DomainEvent, error) {
+	fields, err := personalFieldsOf(event)
+	if err != nil || fields == nil {
+		return event, err
+	}
+
+	clone, value := cloneEventValue(event)
+	subjectID := fields.subjectID(value, event.GetMetadata())
+	eventID := event.GetMetadata().EventID
+
+	var aead cipher.AEAD
+	forgotten := false
+	for _, field := range fields.personal {
+		target := value.Field(field.index)
+		stored, ok := strings.CutPrefix(string(fieldBytes(target)), shreddedPrefix)
+		if !ok {
+			continue
+		}
+
+		if aead == nil && !forgotten {
+			key, err := s.keys.SubjectKey(ctx, subjectID, false)
+			switch {
+			case errors.Is(err, ErrSubjectForgotten):
+				forgotten = true
+			case err != nil:
+				return nil, err
+			default:
+				if aead, err = newAEAD(key); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if forgotten {
+			redactField(target)
+			continue
+		}
+
+		sealed, err := base64.StdEncoding.DecodeString(stored)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return nil, fmt.Errorf("decode personal field %s of event %s: malformed ciphertext", field.name, eventID)
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, shreddedAdditionalData(eventID, field.name))
+		if err != nil {
+			return nil, fmt.Errorf("decrypt personal field %s of event %s: %w", field.name, eventID, err)
+		}
+		setFieldBytes(target, plaintext)
+	}
+	return clone, nil
+}
+
+// shreddedAdditionalData binds a sealed field to its event and field name.
+func shreddedAdditionalData(eventID uuid.UUID, field string) []byte {
+	data := make([]byte, 0, len(eventID)+len(field))
+	data = append(data, eventID[:]...)
+	return append(data, field...)
+}
+
+// personalFields is the cached tag layout of one event struct type.
+type personalFields struct {
+	subject  int
+	personal []personalField
+}
+
+type personalField struct {
+	index int
+	name  string
+}
+
+// personalFieldCache maps event struct types to *personalFields, or to a nil
+// *personalFields when the type has no personal fields.
+var personalFieldCache sync.Map
+
+// personalFieldsOf returns the personal field layout of the event's struct type,
+// or nil when the event carries no personal data.
+func personalFieldsOf(event DomainEvent) (*personalFields, error) {
+	eventType := reflect.TypeOf(event)
+	if eventType == nil || eventType.Kind() != reflect.Pointer || eventType.Elem().Kind() != reflect.Struct {
+		return nil, nil
+	}
+	eventType = eventType.Elem()
+
+	if cached, ok := personalFieldCache.Load(eventType); ok {
+		return cached.(*personalFields), nil
+	}
+
+	fields := &personalFields{subject: -1}
+	for i := range eventType.NumField() {
+		field := eventType.Field(i)
+		switch field.Tag.Get("es") {
+		case personalTag:
+			if !isByteField(field.Type) {
+				return nil, fmt.Errorf("personal field %s.%s must be a string or []byte, got %s", eventType, field.Name, field.Type)
+			}
+			fields.personal = append(fields.personal, personalField{index: i, name: field.Name})
+		case subjectTag:
+			fields.subject = i
+		}
+	}
+	if len(fields.personal) == 0 {
+		fields = nil
+	}
+	personalFieldCache.Store(eventType, fields)
+	return fields, nil
+}
+
+// subjectID returns the data subject of an event, defaulting to its entity ID.
+func (f *personalFields) subjectID(value reflect.Value, metadata EventMetadata) string {
+	if f.subject >= 0 {
+		var id string
+		switch subject := value.Field(f.subject).Interface().(type) {
+		case uuid.UUID:
+			if subject != uuid.Nil {
+				id = subject.String()
+			}
+		case string:
+			id = subject
+		case fmt.Stringer:
+			id = subject.String()
+		}
+		if id != "" {
+			return id
+		}
+	}
+	return metadata.Entity.ID.String()
+}
+
+// cloneEventValue returns a shallow copy of a pointer-to-struct event and its
+// addressable struct value.
+func cloneEventValue(event DomainEvent) (DomainEvent, reflect.Value) {
+	original := reflect.ValueOf(event).Elem()
+	clone := reflect.New(original.Type())
+	clone.Elem().Set(original)
+	return clone.Interface().(DomainEvent), clone.Elem()
+}
+
+func isByteField(t reflect.Type) bool {
+	return t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+}
+
+func fieldBytes(v reflect.Value) []byte {
+	if v.Kind() == reflect.String {
+		return []byte(v.String())
+	}
+	return v.Bytes()
+}
+
+func setFieldBytes(v reflect.Value, data []byte) {
+	if v.Kind() == reflect.String {
+		v.SetString(string(data))
+		return
+	}
+	v.SetBytes(data)
+}
+
+func redactField(v reflect.Value) {
+	if v.Kind() == reflect.String {
+		v.SetString(RedactedValue)
+		return
+	}
+	v.SetBytes(nil)
+}
+
+// InMemorySubjectKeyStore is a SubjectKeyStore that keeps keys in memory.
+// It is intended for tests and development. It is safe for concurrent use.
+type InMemorySubjectKeyStore struct {
+	mu        sync.Mutex
+	keys      map[string][]byte
+	forgotten map[string]struct{}
+}
+
+// NewInMemorySubjectKeyStore creates an empty in-memory subject key store.
+func NewInMemorySubjectKeyStore() *InMemorySubjectKeyStore {
+	return &InMemorySubjectKeyStore{
+		keys:      make(map[string][]byte),
+		forgotten: make(map[string]struct{}),
+	}
+}
+
+// SubjectKey implements SubjectKeyStore. New keys are random 256-bit AES keys.
+func (s *InMemorySubjectKeyStore) SubjectKey(ctx context.Context, subjectID string, create bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.forgotten[subjectID]; ok {
+		return nil, subjectForgottenError(subjectID)
+	}
+	if key, ok := s.keys[subjectID]; ok {
+		return key, nil
+	}
+	if !create {
+		return nil, wrapSentinelError(fmt.Sprintf("no key for subject %s", subjectID), ErrKeyNotFound)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	s.keys[subjectID] = key
+	return key, nil
+}
+
+// ForgetSubject implements SubjectKeyStore. The subject is remembered as forgotten
+// so later writes for it fail instead of silently creating a new key.
+func (s *InMemorySubjectKeyStore) ForgetSubject(ctx context.Context, subjectID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, subjectID)
+	s.forgotten[subjectID] = struct{}{}
+	return nil
+}
+
+func subjectForgottenError(subjectID string) error {
+	return wrapSentinelError(fmt.Sprintf("subject %s has been forgotten", subjectID), ErrSubjectForgotten)
+}
@@ -0,0 +1,194 @@
+package es
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type CustomerRegistered struct {
+	DomainEventBase
+	CustomerID uuid.UUID `es:"subject"`
+	Email      string    `es:"personal"`
+	Photo      []byte    `es:"personal"`
+	Plan       string
+}
+
+func (e *CustomerRegistered) GetDiscriminator() string { return "customer_registered" }
+func (e *CustomerRegistered) GetAreas() []string       { return []string{AreaDummy} }
+func (e *CustomerRegistered) GetSpaces() []string      { return e.GetAreas() }
+
+type untaggableCustomerRegistered struct {
+	DomainEventBase
+	Age int `es:"personal"`
+}
+
+func (e *untaggableCustomerRegistered) GetDiscriminator() string {
+	return "untaggable_customer_registered"
+}
+func (e *untaggableCustomerRegistered) GetAreas() []string  { return []string{AreaDummy} }
+func (e *untaggableCustomerRegistered) GetSpaces() []string { return e.GetAreas() }
+
+type Customer struct {
+	Aggregate
+	email string
+}
+
+func NewCustomer(id uuid.UUID) *Customer {
+	customer := &Customer{Aggregate: NewAggregate(context.Background(), AreaDummy, id)}
+	RegisterHandler(customer, customer.OnCustomerRegistered)
+	return customer
+}
+
+func (a *Customer) Register(email string) error {
+	return a.Raise(&CustomerRegistered{CustomerID: a.GetAggregateID(), Email: email, Photo: []byte("jpeg"), Plan: "pro"})
+}
+
+func (a *Customer) OnCustomerRegistered(e *CustomerRegistered) {
+	a.email = e.Email
+}
+
+func newCustomerRegistered(entity Entity, subjectID uuid.UUID) *CustomerRegistered {
+	event := &CustomerRegistered{CustomerID: subjectID, Email: "ada@example.com", Photo: []byte("jpeg"), Plan: "pro"}
+	event.SetMetadata(EventMetadata{Entity: entity, EventID: uuid.New(), Sequence: 1})
+	return event
+}
+
+func TestShouldEncryptPersonalFieldsAtRest(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	store := NewShreddingStore(inner, NewInMemorySubjectKeyStore())
+	entity := NewEntity(uuid.New(), AreaDummy)
+	event := newCustomerRegistered(entity, entity.ID)
+
+	// Act
+	err := store.SaveEvents(ctx, entity, []DomainEvent{event}, 0)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", event.Email, "caller's event must keep plaintext")
+	raw, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	stored := raw[0].(*CustomerRegistered)
+	assert.NotContains(t, stored.Email, "ada")
+	assert.NotEqual(t, []byte("jpeg"), stored.Photo)
+	assert.Equal(t, "pro", stored.Plan)
+
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []DomainEvent{event}, loaded)
+}
+
+func TestShouldRedactPersonalFieldsAfterForgettingSubject(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewShreddingStore(NewInMemoryEventStore(), NewInMemorySubjectKeyStore())
+	subjectID := uuid.New()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, []DomainEvent{newCustomerRegistered(entity, subjectID)}, 0))
+
+	// Act
+	err := store.ForgetSubject(ctx, subjectID.String())
+
+	// Assert
+	require.NoError(t, err)
+	loaded, err := store.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	redacted := loaded[0].(*CustomerRegistered)
+	assert.Equal(t, RedactedValue, redacted.Email)
+	assert.Nil(t, redacted.Photo)
+	assert.Equal(t, "pro", redacted.Plan)
+
+	for event, err := range store.LoadEventStream(ctx, entity, 0) {
+		require.NoError(t, err)
+		assert.Equal(t, RedactedValue, event.(*CustomerRegistered).Email)
+	}
+}
+
+func TestShouldFailToReadPersonalFieldsWhenSubjectKeyIsMissing(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	entity := NewEntity(uuid.New(), AreaDummy)
+	writer := NewShreddingStore(inner, NewInMemorySubjectKeyStore())
+	require.NoError(t, writer.SaveEvents(ctx, entity, []DomainEvent{newCustomerRegistered(entity, uuid.New())}, 0))
+	reader := NewShreddingStore(inner, NewInMemorySubjectKeyStore())
+
+	// Act
+	_, err := reader.LoadEvents(ctx, entity, 0)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestShouldLoadRedactedAggregateAfterForgettingSubject(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewShreddingStore(NewInMemoryEventStore(), NewInMemorySubjectKeyStore())
+	repository := NewRepository(store)
+	id := uuid.New()
+	customer := NewCustomer(id)
+	require.NoError(t, customer.Register("ada@example.com"))
+	require.NoError(t, repository.Save(ctx, customer))
+	require.NoError(t, store.ForgetSubject(ctx, id.String()))
+
+	// Act
+	loaded := NewCustomer(id)
+	err := repository.Load(ctx, loaded)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, RedactedValue, loaded.email)
+}
+
+func TestShouldRejectPersonalDataForForgottenSubject(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	keys := NewInMemorySubjectKeyStore()
+	store := NewShreddingStore(NewInMemoryEventStore(), keys)
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, keys.ForgetSubject(ctx, entity.ID.String()))
+
+	// Act
+	err := store.SaveEvents(ctx, entity, []DomainEvent{newCustomerRegistered(entity, uuid.Nil)}, 0)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrSubjectForgotten)
+}
+
+func TestShouldRejectPersonalTagOnUnsupportedField(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewShreddingStore(NewInMemoryEventStore(), NewInMemorySubjectKeyStore())
+	entity := NewEntity(uuid.New(), AreaDummy)
+	event := &untaggableCustomerRegistered{Age: 42}
+	event.SetMetadata(EventMetadata{Entity: entity, EventID: uuid.New(), Sequence: 1})
+
+	// Act
+	err := store.SaveEvents(ctx, entity, []DomainEvent{event}, 0)
+
+	// Assert
+	assert.ErrorContains(t, err, "must be a string or []byte")
+}
+
+func TestShouldPassThroughEventsWithoutPersonalFields(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := NewInMemoryEventStore()
+	store := NewShreddingStore(inner, NewInMemorySubjectKeyStore())
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 2)
+
+	// Act
+	err := store.SaveEvents(ctx, entity, events, 0)
+
+	// Assert
+	require.NoError(t, err)
+	raw, err := inner.LoadEvents(ctx, entity, 0)
+	require.NoError(t, err)
+	assert.Equal(t, events, raw)
+}
@@ -1,6 +1,12 @@
 package es
 
-import "context"
+import (
+	"cmp"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+)
 
 // Store defines the interface for persisting and retrieving domain events.
 // Implementations should handle concurrent access and ensure consistency.
@@ -13,3 +19,197 @@ type Store interface {
 	// Returns empty slice if no events are found.
 	LoadEvents(ctx context.Context, entity Entity, minSequence uint64) ([]DomainEvent, error)
 }
+
+// StreamingStore is an optional Store capability for reading a stream incrementally
+// instead of materializing it with LoadEvents.
+type StreamingStore interface {
+	Store
+
+	// LoadEventStream yields events for an entity starting from minSequence, in order.
+	// Implementations should hold at most a bounded number of events ahead of the
+	// consumer and must stop when the consumer stops iterating. A failure is yielded
+	// once with a nil event and ends the iteration.
+	LoadEventStream(ctx context.Context, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error]
+}
+
+// StreamEvents reads an entity's events incrementally when the store implements
+// StreamingStore, and falls back to LoadEvents otherwise.
+func StreamEvents(ctx context.Context, store Store, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error] {
+	if streaming, ok := store.(StreamingStore); ok {
+		return streaming.LoadEventStream(ctx, entity, minSequence)
+	}
+
+	return func(yield func(DomainEvent, error) bool) {
+		events, err := store.LoadEvents(ctx, entity, minSequence)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, event := range events {
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+// StreamVersioner is an optional Store capability for reading a stream's head
+// sequence without reading its events.
+type StreamVersioner interface {
+	Store
+
+	// StreamVersion returns the head sequence of a stream and whether it has any
+	// events. Soft-deleted streams return ErrStreamDeleted.
+	StreamVersion(ctx context.Context, entity Entity) (uint64, bool, error)
+}
+
+// StreamVersion returns the head sequence of an entity's stream and whether it has
+// any events. It asks a StreamVersioner or StreamMetadataStore when available, and
+// otherwise reads the last event.
+func StreamVersion(ctx context.Context, store Store, entity Entity) (uint64, bool, error) {
+	if versioner, ok := store.(StreamVersioner); ok {
+		return versioner.StreamVersion(ctx, entity)
+	}
+
+	if metadataStore, ok := store.(StreamMetadataStore); ok {
+		metadata, _, err := metadataStore.GetStreamMetadata(ctx, entity)
+		if err != nil {
+			return 0, false, err
+		}
+		return metadata.LastSequence, metadata.Exists(), nil
+	}
+
+	events, err := ReadStream(ctx, store, entity, ReadOptions{Limit: 1, Backwards: true})
+	if err != nil {
+		return 0, false, err
+	}
+	if len(events) == 0 {
+		return 0, false, nil
+	}
+	return events[0].GetSequence(), true, nil
+}
+
+// StreamCopier is an optional Store capability for recreating streams copied from
+// another store, as Migrator and Import do.
+type StreamCopier interface {
+	Store
+
+	// CopyEvents appends events copied from another store. It behaves like SaveEvents
+	// with startSequence as the expected sequence, except that a missing stream is
+	// created at startSequence, so a source stream that was truncated or trimmed by
+	// retention keeps its sequences in the copy.
+	CopyEvents(ctx context.Context, entity Entity, events []DomainEvent, startSequence uint64) error
+}
+
+// CopyEvents appends copied events through StreamCopier when the store implements
+// it, and through SaveEvents otherwise. Without the capability, a missing stream
+// can only be created at sequence zero; other start sequences fail with an error
+// matching ErrUnsupported.
+func CopyEvents(ctx context.Context, store Store, entity Entity, events []DomainEvent, startSequence uint64) error {
+	if copier, ok := store.(StreamCopier); ok {
+		return copier.CopyEvents(ctx, entity, events, startSequence)
+	}
+
+	err := store.SaveEvents(ctx, entity, events, startSequence)
+	if startSequence == 0 || !errors.Is(err, ErrConcurrency) {
+		return err
+	}
+	if _, exists, versionErr := StreamVersion(ctx, store, entity); versionErr == nil && !exists {
+		return unsupportedError(store, "copying streams that start above sequence 1")
+	}
+	return err
+}
+
+// StreamDeleter is an optional Store capability for retiring and removing streams.
+type StreamDeleter interface {
+	Store
+
+	// SoftDeleteStream tombstones a stream. Its events become unreadable and later
+	// reads and appends fail with ErrStreamDeleted. Deleting a missing or already
+	// deleted stream succeeds.
+	SoftDeleteStream(ctx context.Context, entity Entity) error
+
+	// HardDeleteStream removes a stream and any tombstone, as if it had never been
+	// written. It is intended for tests and data-erasure requests.
+	HardDeleteStream(ctx context.Context, entity Entity) error
+
+	// TruncateStreamBefore removes events with a sequence lower than sequence.
+	// The stream's head sequence is unchanged, so appends continue from where
+	// they left off. Use it only when the aggregate state for the removed prefix
+	// is held elsewhere, such as in a snapshot.
+	TruncateStreamBefore(ctx context.Context, entity Entity, sequence uint64) error
+}
+
+// ReadOptions bounds a ReadStream call. The zero value reads the whole stream forwards.
+type ReadOptions struct {
+	// From is the lowest sequence to include. Zero starts at the beginning of the stream.
+	From uint64
+	// To is the highest sequence to include. Zero reads through the head of the stream.
+	To uint64
+	// Limit caps the number of events returned. Zero or less means no limit.
+	Limit int
+	// Backwards returns events newest first. Combined with Limit it selects the
+	// last Limit events of the range.
+	Backwards bool
+}
+
+// StreamReader is an optional Store capability for bounded, paged, or reverse reads.
+type StreamReader interface {
+	Store
+
+	// ReadStream returns the events of an entity selected by opts.
+	// Returns an empty slice if no events match.
+	ReadStream(ctx context.Context, entity Entity, opts ReadOptions) ([]DomainEvent, error)
+}
+
+// ReadStream reads a range of an entity's events through StreamReader when the store
+// implements it, and otherwise loads from opts.From and applies the remaining options in memory.
+func ReadStream(ctx context.Context, store Store, entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	if reader, ok := store.(StreamReader); ok {
+		return reader.ReadStream(ctx, entity, opts)
+	}
+
+	events, err := store.LoadEvents(ctx, entity, opts.From)
+	if err != nil {
+		return nil, err
+	}
+	return applyReadOptions(events, opts), nil
+}
+
+// applyReadOptions selects the events matching opts from a slice ordered by sequence.
+// The result never aliases the input.
+func applyReadOptions(events []DomainEvent, opts ReadOptions) []DomainEvent {
+	start := searchSequence(events, opts.From)
+	end := len(events)
+	if opts.To > 0 {
+		end = searchSequence(events, opts.To+1)
+	}
+	if start > end {
+		start = end
+	}
+
+	window := events[start:end]
+	if opts.Limit > 0 && len(window) > opts.Limit {
+		if opts.Backwards {
+			window = window[len(window)-opts.Limit:]
+		} else {
+			window = window[:opts.Limit]
+		}
+	}
+
+	result := make([]DomainEvent, len(window))
+	copy(result, window)
+	if opts.Backwards {
+		slices.Reverse(result)
+	}
+	return result
+}
+
+// searchSequence returns the index of the first event at or after sequence in a
+// slice ordered by sequence.
+func searchSequence(events []DomainEvent, sequence uint64) int {
+	index, _ := slices.BinarySearchFunc(events, sequence, func(event DomainEvent, target uint64) int {
+		return cmp.Compare(event.GetSequence(), target)
+	})
+	return index
+}
@@ -0,0 +1,291 @@
+package es
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// StoreMiddleware wraps a Store to add behavior such as tracing, caching,
+// validation, or fault injection without changing the wrapped implementation.
+type StoreMiddleware func(Store) Store
+
+// ChainStore wraps store with the given middleware. The first middleware is the
+// outermost: ChainStore(s, a, b) returns a(b(s)).
+func ChainStore(store Store, mws ...StoreMiddleware) Store {
+	for i := len(mws) - 1; i >= 0; i-- {
+		store = mws[i](store)
+	}
+	return store
+}
+
+// ForwardingStore forwards Store calls and every optional store capability to Next.
+// Embed it in a decorator and override only the methods the decorator changes.
+//
+// Read capabilities and CopyEvents always work: when Next lacks them,
+// ForwardingStore falls back the same way StreamEvents, ReadStream, StreamVersion,
+// and CopyEvents do. StreamDeleter, StreamMetadataStore, and StreamLister calls
+// return ErrUnsupported when Next does not implement them.
+//
+// Because Go has no virtual dispatch through embedding, a decorator that changes
+// events on the way out must override LoadEvents, LoadEventStream, and ReadStream,
+// and one that changes events on the way in must override SaveEvents and CopyEvents.
+type ForwardingStore struct {
+	Next Store
+}
+
+// SaveEvents implements Store.SaveEvents.
+func (s ForwardingStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
+	return s.Next.SaveEvents(ctx, entity, events, expectedSequence)
+}
+
+// LoadEvents implements Store.LoadEvents.
+func (s ForwardingStore) LoadEvents(ctx context.Context, entity Entity, minSequence uint64) ([]DomainEvent, error) {
+	return s.Next.LoadEvents(ctx, entity, minSequence)
+}
+
+// LoadEventStream implements StreamingStore.LoadEventStream.
+func (s ForwardingStore) LoadEventStream(ctx context.Context, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error] {
+	return StreamEvents(ctx, s.Next, entity, minSequence)
+}
+
+// ReadStream implements StreamReader.ReadStream.
+func (s ForwardingStore) ReadStream(ctx context.Context, entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	return ReadStream(ctx, s.Next, entity, opts)
+}
+
+// StreamVersion implements StreamVersioner.StreamVersion.
+func (s ForwardingStore) StreamVersion(ctx context.Context, entity Entity) (uint64, bool, error) {
+	return StreamVersion(ctx, s.Next, entity)
+}
+
+// CopyEvents implements StreamCopier.CopyEvents.
+func (s ForwardingStore) CopyEvents(ctx context.Context, entity Entity, events []DomainEvent, startSequence uint64) error {
+	return CopyEvents(ctx, s.Next, entity, events, startSequence)
+}
+
+// SoftDeleteStream implements StreamDeleter.SoftDeleteStream.
+func (s ForwardingStore) SoftDeleteStream(ctx context.Context, entity Entity) error {
+	deleter, ok := s.Next.(StreamDeleter)
+	if !ok {
+		return unsupportedError(s.Next, "stream deletion")
+	}
+	return deleter.SoftDeleteStream(ctx, entity)
+}
+
+// HardDeleteStream implements StreamDeleter.HardDeleteStream.
+func (s ForwardingStore) HardDeleteStream(ctx context.Context, entity Entity) error {
+	deleter, ok := s.Next.(StreamDeleter)
+	if !ok {
+		return unsupportedError(s.Next, "stream deletion")
+	}
+	return deleter.HardDeleteStream(ctx, entity)
+}
+
+// TruncateStreamBefore implements StreamDeleter.TruncateStreamBefore.
+func (s ForwardingStore) TruncateStreamBefore(ctx context.Context, entity Entity, sequence uint64) error {
+	deleter, ok := s.Next.(StreamDeleter)
+	if !ok {
+		return unsupportedError(s.Next, "stream deletion")
+	}
+	return deleter.TruncateStreamBefore(ctx, entity, sequence)
+}
+
+// GetStreamMetadata implements StreamMetadataStore.GetStreamMetadata.
+func (s ForwardingStore) GetStreamMetadata(ctx context.Context, entity Entity) (StreamMetadata, bool, error) {
+	metadataStore, ok := s.Next.(StreamMetadataStore)
+	if !ok {
+		return StreamMetadata{}, false, unsupportedError(s.Next, "stream metadata")
+	}
+	return metadataStore.GetStreamMetadata(ctx, entity)
+}
+
+// SetStreamSettings implements StreamMetadataStore.SetStreamSettings.
+func (s ForwardingStore) SetStreamSettings(ctx context.Context, entity Entity, settings StreamSettings) error {
+	metadataStore, ok := s.Next.(StreamMetadataStore)
+	if !ok {
+		return unsupportedError(s.Next, "stream metadata")
+	}
+	return metadataStore.SetStreamSettings(ctx, entity, settings)
+}
+
+// ListStreams implements StreamLister.ListStreams.
+func (s ForwardingStore) ListStreams(ctx context.Context, filter StreamFilter) (StreamPage, error) {
+	lister, ok := s.Next.(StreamLister)
+	if !ok {
+		return StreamPage{}, unsupportedError(s.Next, "stream listing")
+	}
+	return lister.ListStreams(ctx, filter)
+}
+
+func unsupportedError(store Store, capability string) error {
+	return wrapSentinelError(fmt.Sprintf("store %T does not support %s", store, capability), ErrUnsupported)
+}
+
+// TracingStore is a StoreMiddleware that emits an OpenTelemetry span for each
+// store read and append. Spans carry the same entity and correlation attributes
+// as repository spans, plus event counts and expected sequences.
+func TracingStore(next Store) Store {
+	return &tracingStore{ForwardingStore{Next: next}}
+}
+
+type tracingStore struct {
+	ForwardingStore
+}
+
+func (s *tracingStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
+	ctx, span := startSpan(ctx, spanStoreSaveEvents, entity,
+		attribute.Int(attributeEventsCount, len(events)),
+		attribute.String(attributeSequenceExpected, strconv.FormatUint(expectedSequence, 10)),
+	)
+	defer span.End()
+
+	return recordSpanError(span, s.Next.SaveEvents(ctx, entity, events, expectedSequence))
+}
+
+func (s *tracingStore) LoadEvents(ctx context.Context, entity Entity, minSequence uint64) ([]DomainEvent, error) {
+	ctx, span := startSpan(ctx, spanStoreLoadEvents, entity)
+	defer span.End()
+
+	events, err := s.Next.LoadEvents(ctx, entity, minSequence)
+	span.SetAttributes(attribute.Int(attributeEventsCount, len(events)))
+	return events, recordSpanError(span, err)
+}
+
+func (s *tracingStore) LoadEventStream(ctx context.Context, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error] {
+	return func(yield func(DomainEvent, error) bool) {
+		ctx, span := startSpan(ctx, spanStoreLoadEventStream, entity)
+		defer span.End()
+
+		count := 0
+		for event, err := range StreamEvents(ctx, s.Next, entity, minSequence) {
+			if err != nil {
+				recordSpanError(span, err)
+			} else {
+				count++
+			}
+			if !yield(event, err) {
+				break
+			}
+		}
+		span.SetAttributes(attribute.Int(attributeEventsCount, count))
+	}
+}
+
+func (s *tracingStore) ReadStream(ctx context.Context, entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	ctx, span := startSpan(ctx, spanStoreReadStream, entity)
+	defer span.End()
+
+	events, err := ReadStream(ctx, s.Next, entity, opts)
+	span.SetAttributes(attribute.Int(attributeEventsCount, len(events)))
+	return events, recordSpanError(span, err)
+}
+
+// Store operation names reported to a StoreRecorder.
+const (
+	StoreOperationSaveEvents      = "save_events"
+	StoreOperationLoadEvents      = "load_events"
+	StoreOperationLoadEventStream = "load_event_stream"
+	StoreOperationReadStream      = "read_stream"
+	StoreOperationStreamVersion   = "stream_version"
+)
+
+// StoreCall describes one completed store operation.
+type StoreCall struct {
+	Operation string
+	Entity    Entity
+	// Events is the number of events appended or returned.
+	Events   int
+	Duration time.Duration
+	Err      error
+}
+
+// StoreRecorder receives a StoreCall after each observed store operation.
+// Implementations typically feed latency histograms and error counters.
+type StoreRecorder interface {
+	RecordStoreCall(ctx context.Context, call StoreCall)
+}
+
+// StoreRecorderFunc adapts a function to the StoreRecorder interface.
+type StoreRecorderFunc func(ctx context.Context, call StoreCall)
+
+// RecordStoreCall implements StoreRecorder.
+func (f StoreRecorderFunc) RecordStoreCall(ctx context.Context, call StoreCall) { f(ctx, call) }
+
+// RecordingStore returns a StoreMiddleware that reports the latency, size, and
+// outcome of every read and append to recorder. For LoadEventStream the duration
+// covers the whole iteration.
+func RecordingStore(recorder StoreRecorder) StoreMiddleware {
+	return func(next Store) Store {
+		return &recordingStore{ForwardingStore: ForwardingStore{Next: next}, recorder: recorder}
+	}
+}
+
+type recordingStore struct {
+	ForwardingStore
+	recorder StoreRecorder
+}
+
+func (s *recordingStore) record(ctx context.Context, operation string, entity Entity, started time.Time, events int, err error) {
+	s.recorder.RecordStoreCall(ctx, StoreCall{
+		Operation: operation,
+		Entity:    entity,
+		Events:    events,
+		Duration:  time.Since(started),
+		Err:       err,
+	})
+}
+
+func (s *recordingStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
+	started := time.Now()
+	err := s.Next.SaveEvents(ctx, entity, events, expectedSequence)
+	s.record(ctx, StoreOperationSaveEvents, entity, started, len(events), err)
+	return err
+}
+
+func (s *recordingStore) LoadEvents(ctx context.Context, entity Entity, minSequence uint64) ([]DomainEvent, error) {
+	started := time.Now()
+	events, err := s.Next.LoadEvents(ctx, entity, minSequence)
+	s.record(ctx, StoreOperationLoadEvents, entity, started, len(events), err)
+	return events, err
+}
+
+func (s *recordingStore) LoadEventStream(ctx context.Context, entity Entity, minSequence uint64) iter.Seq2[DomainEvent, error] {
+	return func(yield func(DomainEvent, error) bool) {
+		started := time.Now()
+		count := 0
+		var streamErr error
+		defer func() {
+			s.record(ctx, StoreOperationLoadEventStream, entity, started, count, streamErr)
+		}()
+
+		for event, err := range StreamEvents(ctx, s.Next, entity, minSequence) {
+			if err != nil {
+				streamErr = err
+			} else {
+				count++
+			}
+			if !yield(event, err) {
+				return
+			}
+		}
+	}
+}
+
+func (s *recordingStore) ReadStream(ctx context.Context, entity Entity, opts ReadOptions) ([]DomainEvent, error) {
+	started := time.Now()
+	events, err := ReadStream(ctx, s.Next, entity, opts)
+	s.record(ctx, StoreOperationReadStream, entity, started, len(events), err)
+	return events, err
+}
+
+func (s *recordingStore) StreamVersion(ctx context.Context, entity Entity) (uint64, bool, error) {
+	started := time.Now()
+	version, exists, err := StreamVersion(ctx, s.Next, entity)
+	s.record(ctx, StoreOperationStreamVersion, entity, started, 0, err)
+	return version, exists, err
+}
@@ -0,0 +1,149 @@
+package es
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type labelingStore struct {
+	ForwardingStore
+	label string
+	calls *[]string
+}
+
+func (s *labelingStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
+	*s.calls = append(*s.calls, s.label)
+	return s.Next.SaveEvents(ctx, entity, events, expectedSequence)
+}
+
+func labeling(label string, calls *[]string) StoreMiddleware {
+	return func(next Store) Store {
+		return &labelingStore{ForwardingStore: ForwardingStore{Next: next}, label: label, calls: calls}
+	}
+}
+
+func TestShouldApplyStoreMiddlewareOutermostFirst(t *testing.T) {
+	// Arrange
+	var calls []string
+	store := ChainStore(NewInMemoryEventStore(), labeling("outer", &calls), labeling("inner", &calls))
+	entity := NewEntity(uuid.New(), AreaDummy)
+
+	// Act
+	err := store.SaveEvents(context.Background(), entity, newDummyEvents(entity, 1), 0)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer", "inner"}, calls)
+}
+
+func TestShouldForwardOptionalCapabilitiesThroughMiddleware(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := ChainStore(NewInMemoryEventStore(), TracingStore)
+	repo := NewRepository(store)
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 3), 0))
+
+	// Act
+	err := repo.SoftDelete(ctx, entity)
+
+	// Assert
+	require.NoError(t, err)
+	_, loadErr := store.LoadEvents(ctx, entity, 0)
+	assert.ErrorIs(t, loadErr, ErrStreamDeleted)
+}
+
+func TestShouldReturnUnsupportedWhenWrappedStoreLacksCapability(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := ForwardingStore{Next: basicStore{inner: NewInMemoryEventStore()}}
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 3), 0))
+
+	// Act
+	deleteErr := store.SoftDeleteStream(ctx, entity)
+	_, _, metadataErr := store.GetStreamMetadata(ctx, entity)
+	_, listErr := store.ListStreams(ctx, StreamFilter{})
+	events, readErr := store.ReadStream(ctx, entity, ReadOptions{Limit: 1, Backwards: true})
+
+	// Assert
+	assert.ErrorIs(t, deleteErr, ErrUnsupported)
+	assert.ErrorIs(t, metadataErr, ErrUnsupported)
+	assert.ErrorIs(t, listErr, ErrUnsupported)
+	require.NoError(t, readErr)
+	assert.Equal(t, []uint64{3}, sequencesOf(events))
+}
+
+func TestShouldEmitSpansForStoreCalls(t *testing.T) {
+	// Arrange
+	spanRecorder := setupSpanRecorder(t)
+	correlationID := uuid.New()
+	causationID := uuid.New()
+	ctx := ContextWithTracing(context.Background(), correlationID, causationID)
+	store := TracingStore(NewInMemoryEventStore())
+	entity := NewEntity(uuid.New(), AreaDummy)
+
+	// Act
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 2), 0))
+	_, err := store.LoadEvents(ctx, entity, 0)
+
+	// Assert
+	require.NoError(t, err)
+	spans := spanRecorder.Ended()
+	require.Len(t, spans, 2)
+	assertRepositorySpanAttributes(t, spans[0], spanStoreSaveEvents, entity, correlationID, causationID)
+	assertSpanInt64Attribute(t, spans[0], attributeEventsCount, 2)
+	assertSpanStringAttribute(t, spans[0], attributeSequenceExpected, "0")
+	assertRepositorySpanAttributes(t, spans[1], spanStoreLoadEvents, entity, correlationID, causationID)
+	assertSpanInt64Attribute(t, spans[1], attributeEventsCount, 2)
+}
+
+func TestShouldRecordSpanErrorWhenStoreSaveFails(t *testing.T) {
+	// Arrange
+	spanRecorder := setupSpanRecorder(t)
+	store := TracingStore(NewInMemoryEventStore())
+	entity := NewEntity(uuid.New(), AreaDummy)
+
+	// Act
+	err := store.SaveEvents(context.Background(), entity, newDummyEvents(entity, 1), 5)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrConcurrency)
+	spans := spanRecorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestShouldRecordStoreCallLatencyAndErrors(t *testing.T) {
+	// Arrange
+	var calls []StoreCall
+	recorder := StoreRecorderFunc(func(ctx context.Context, call StoreCall) {
+		calls = append(calls, call)
+	})
+	store := ChainStore(NewInMemoryEventStore(), RecordingStore(recorder))
+	ctx := context.Background()
+	entity := NewEntity(uuid.New(), AreaDummy)
+
+	// Act
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 2), 0))
+	saveErr := store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0)
+	for _, err := range StreamEvents(ctx, store, entity, 0) {
+		require.NoError(t, err)
+	}
+
+	// Assert
+	assert.ErrorIs(t, saveErr, ErrConcurrency)
+	require.Len(t, calls, 3)
+	assert.Equal(t, StoreOperationSaveEvents, calls[0].Operation)
+	assert.Equal(t, 2, calls[0].Events)
+	assert.NoError(t, calls[0].Err)
+	assert.ErrorIs(t, calls[1].Err, ErrConcurrency)
+	assert.Equal(t, StoreOperationLoadEventStream, calls[2].Operation)
+	assert.Equal(t, 2, calls[2].Events)
+	assert.Equal(t, entity, calls[2].Entity)
+}
@@ -0,0 +1,88 @@
+package es
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// basicStore exposes only the Store interface of the wrapped store so tests can
+// exercise the fallbacks used for stores without optional capabilities.
+type basicStore struct {
+	inner Store
+}
+
+func (s basicStore) SaveEvents(ctx context.Context, entity Entity, events []DomainEvent, expectedSequence uint64) error {
+	return s.inner.SaveEvents(ctx, entity, events, expectedSequence)
+}
+
+func (s basicStore) LoadEvents(ctx context.Context, entity Entity, minSequence uint64) ([]DomainEvent, error) {
+	return s.inner.LoadEvents(ctx, entity, minSequence)
+}
+
+func TestShouldStreamEventsFromStoreWithoutStreamingSupport(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := basicStore{inner: NewInMemoryEventStore()}
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 3), 0))
+
+	// Act
+	var sequences []uint64
+	for event, err := range StreamEvents(ctx, store, entity, 2) {
+		require.NoError(t, err)
+		sequences = append(sequences, event.GetSequence())
+	}
+
+	// Assert
+	assert.Equal(t, []uint64{2, 3}, sequences)
+}
+
+func TestShouldReadStreamFromStoreWithoutRangeSupport(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := basicStore{inner: NewInMemoryEventStore()}
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 6), 0))
+
+	// Act
+	events, err := ReadStream(ctx, store, entity, ReadOptions{From: 2, To: 5, Limit: 2, Backwards: true})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5, 4}, sequencesOf(events))
+}
+
+func TestShouldReadStreamVersionFromLastEventWithoutVersionSupport(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := basicStore{inner: NewInMemoryEventStore()}
+	entity := NewEntity(uuid.New(), AreaDummy)
+	require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 3), 0))
+
+	// Act
+	version, exists, err := StreamVersion(ctx, store, entity)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, uint64(3), version)
+}
+
+func TestShouldRejectCopyAboveFirstSequenceWithoutCopySupport(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := basicStore{inner: NewInMemoryEventStore()}
+	entity := NewEntity(uuid.New(), AreaDummy)
+	events := newDummyEvents(entity, 5)
+
+	// Act
+	err := CopyEvents(ctx, store, entity, events[3:], 3)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.NoError(t, CopyEvents(ctx, store, entity, events, 0))
+}
@@ -0,0 +1,120 @@
+package es
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// defaultStreamPageSize is the page size of ListStreams when StreamFilter.Limit is zero.
+const defaultStreamPageSize = 100
+
+// StreamFilter selects the streams returned by a StreamLister.
+type StreamFilter struct {
+	// Area restricts the listing to one area when set.
+	Area string
+	// Scope restricts the listing to one scope when set.
+	Scope *Scope
+	// TenantID restricts the listing to one tenant's streams when set.
+	TenantID uuid.UUID
+	// IncludeDeleted also lists soft-deleted streams, so tools such as Migrator can
+	// carry their tombstones.
+	IncludeDeleted bool
+	// Limit bounds the page size; zero uses the store's default.
+	Limit int
+	// PageToken continues a listing from StreamPage.NextPageToken.
+	PageToken string
+}
+
+// Matches reports whether an entity passes the filter's area, scope, and tenant.
+func (f StreamFilter) Matches(entity Entity) bool {
+	return (f.Area == "" || entity.Area == f.Area) &&
+		(f.Scope == nil || entity.Scope == *f.Scope) &&
+		(f.TenantID == uuid.Nil || entity.TenantID == f.TenantID)
+}
+
+// StreamPage is one page of a stream listing.
+type StreamPage struct {
+	Entities []Entity
+	// NextPageToken is empty on the last page.
+	NextPageToken string
+}
+
+// StreamLister is an optional Store capability for discovering the streams in a
+// store, for admin tools, migrations, and rebuild jobs.
+type StreamLister interface {
+	Store
+
+	// ListStreams returns one page of the streams that have events and match the
+	// filter, in a stable order. Soft-deleted streams are listed only when
+	// filter.IncludeDeleted is set.
+	ListStreams(ctx context.Context, filter StreamFilter) (StreamPage, error)
+}
+
+// Streams iterates over every stream matching filter, following page tokens from
+// filter.PageToken on. It yields an error matching ErrUnsupported when the store
+// does not implement StreamLister.
+func Streams(ctx context.Context, store Store, filter StreamFilter) iter.Seq2[Entity, error] {
+	return func(yield func(Entity, error) bool) {
+		lister, ok := store.(StreamLister)
+		if !ok {
+			yield(Entity{}, unsupportedError(store, "stream listing"))
+			return
+		}
+
+		for {
+			page, err := lister.ListStreams(ctx, filter)
+			if err != nil {
+				yield(Entity{}, err)
+				return
+			}
+			for _, entity := range page.Entities {
+				if !yield(entity, nil) {
+					return
+				}
+			}
+			if page.NextPageToken == "" {
+				return
+			}
+			filter.PageToken = page.NextPageToken
+		}
+	}
+}
+
+// compareEntities orders entities by area, tenant, ID, and scope.
+func compareEntities(a, b Entity) int {
+	return cmp.Or(
+		strings.Compare(a.Area, b.Area),
+		bytes.Compare(a.TenantID[:], b.TenantID[:]),
+		bytes.Compare(a.ID[:], b.ID[:]),
+		cmp.Compare(a.Scope, b.Scope),
+	)
+}
+
+// encodeStreamPageToken returns an opaque token for the entity a page ended on.
+func encodeStreamPageToken(last Entity) (string, error) {
+	data, err := json.Marshal(last)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeStreamPageToken(token string) (Entity, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Entity{}, fmt.Errorf("invalid page token: %w", err)
+	}
+	var last Entity
+	if err := json.Unmarshal(data, &last); err != nil {
+		return Entity{}, fmt.Errorf("invalid page token: %w", err)
+	}
+	return last, nil
+}
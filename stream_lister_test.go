@@ -0,0 +1,116 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldListStreamsAcrossPages(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	var saved []Entity
+	for range 5 {
+		entity := NewEntity(uuid.New(), AreaDummy)
+		require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0))
+		saved = append(saved, entity)
+	}
+
+	// Act
+	first, err := store.ListStreams(ctx, StreamFilter{Limit: 2})
+	require.NoError(t, err)
+	var all []Entity
+	for entity, err := range Streams(ctx, store, StreamFilter{Limit: 2}) {
+		require.NoError(t, err)
+		all = append(all, entity)
+	}
+
+	// Assert
+	assert.Len(t, first.Entities, 2)
+	assert.NotEmpty(t, first.NextPageToken)
+	assert.ElementsMatch(t, saved, all)
+	assert.IsIncreasing(t, idsOf(all))
+}
+
+func TestShouldFilterListedStreamsByAreaScopeAndTenant(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewInMemoryEventStore().(*InMemoryEventStore)
+	tenant := uuid.New()
+	wanted := NewTenantEntity(tenant, uuid.New(), AreaDummy)
+	otherTenant := NewTenantEntity(uuid.New(), uuid.New(), AreaDummy)
+	otherArea := NewTenantEntity(tenant, uuid.New(), AreaTest)
+	deleted := NewTenantEntity(tenant, uuid.New(), AreaDummy)
+	empty := NewTenantEntity(tenant, uuid.New(), AreaDummy)
+	for _, entity := range []Entity{wanted, otherTenant, otherArea, deleted} {
+		require.NoError(t, store.SaveEvents(ctx, entity, newDummyEvents(entity, 1), 0))
+	}
+	require.NoError(t, store.SoftDeleteStream(ctx, deleted))
+	require.NoError(t, store.SetStreamSettings(ctx, empty, StreamSettings{MaxCount: 1}))
+	scope := wanted.Scope
+
+	// Act
+	page, err := store.ListStreams(ctx, StreamFilter{Area: AreaDummy, Scope: &scope, TenantID: tenant})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []Entity{wanted}, page.Entities)
+	assert.Empty(t, page.NextPageToken)
+}
+
+func TestShouldReturnUnsupportedWhenListingStreamsOfBasicStore(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := basicStore{inner: NewInMemoryEventStore()}
+
+	// Act
+	var errs []error
+	for _, err := range Streams(ctx, store, StreamFilter{}) {
+		errs = append(errs, err)
+	}
+
+	// Assert
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrUnsupported)
+}
+
+func TestShouldExportAndMigrateListedStreams(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	source := NewInMemoryEventStore()
+	first := NewEntity(uuid.New(), AreaDummy)
+	second := NewEntity(uuid.New(), AreaDummy)
+	skipped := NewEntity(uuid.New(), AreaTest)
+	for _, entity := range []Entity{first, second, skipped} {
+		require.NoError(t, source.SaveEvents(ctx, entity, newStampedDummyEvents(entity, 2), 0))
+	}
+	filter := StreamFilter{Area: AreaDummy}
+	target := NewInMemoryEventStore()
+
+	// Act
+	var dump bytes.Buffer
+	exportErr := Export(ctx, source, &dump, ExportFilter{Streams: filter})
+	report, migrateErr := NewMigrator(source, target).MigrateAll(ctx, filter)
+
+	// Assert
+	require.NoError(t, exportErr)
+	assert.Equal(t, 4, bytes.Count(dump.Bytes(), []byte("\n")))
+	require.NoError(t, migrateErr)
+	assert.Len(t, report.Streams, 2)
+	exists, err := NewRepository(target).Exists(ctx, skipped)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func idsOf(entities []Entity) []string {
+	ids := make([]string, len(entities))
+	for i, entity := range entities {
+		ids[i] = entity.ID.String()
+	}
+	return ids
+}
@@ -0,0 +1,64 @@
+package es
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// StreamMetadata describes a stream. The system fields are maintained by the store;
+// Settings are written through StreamMetadataStore.SetStreamSettings.
+type StreamMetadata struct {
+	// CreatedAt is the store timestamp of the first append, or zero if nothing was appended.
+	CreatedAt int64 `json:"created_at"`
+	// LastSequence is the head sequence of the stream. It is zero for streams
+	// that have settings but no events.
+	LastSequence uint64 `json:"last_sequence"`
+	// LastTimestamp is the store timestamp of the most recent append.
+	LastTimestamp int64 `json:"last_timestamp"`
+	// Settings holds the user-managed per-stream configuration.
+	Settings StreamSettings `json:"settings"`
+}
+
+// StreamSettings is the writable part of StreamMetadata.
+type StreamSettings struct {
+	// Custom holds arbitrary key/value properties.
+	Custom map[string]string `json:"custom,omitempty"`
+	// MaxCount retains at most this many of the newest events. Zero keeps all events.
+	MaxCount uint64 `json:"max_count,omitempty"`
+	// MaxAge retains events whose EventMetadata.Timestamp is within MaxAge of the
+	// store clock, in the same units as the timestamp. Zero keeps all events.
+	MaxAge int64 `json:"max_age,omitempty"`
+	// ACL holds access-control tags. Stores record them; enforcing them is up to the caller.
+	ACL []string `json:"acl,omitempty"`
+}
+
+// Exists reports whether the stream has had any events appended.
+func (m StreamMetadata) Exists() bool {
+	return m.LastSequence > 0
+}
+
+func (s StreamSettings) isZero() bool {
+	return len(s.Custom) == 0 && s.MaxCount == 0 && s.MaxAge == 0 && len(s.ACL) == 0
+}
+
+func (s StreamSettings) clone() StreamSettings {
+	s.Custom = maps.Clone(s.Custom)
+	s.ACL = slices.Clone(s.ACL)
+	return s
+}
+
+// StreamMetadataStore is an optional Store capability for per-stream metadata and settings.
+// Retention settings are honored by the store: events outside MaxCount or MaxAge are
+// no longer returned by reads, while the head sequence is unaffected.
+type StreamMetadataStore interface {
+	Store
+
+	// GetStreamMetadata returns the metadata of a stream and whether the store has
+	// any record of it. Soft-deleted streams return ErrStreamDeleted.
+	GetStreamMetadata(ctx context.Context, entity Entity) (StreamMetadata, bool, error)
+
+	// SetStreamSettings replaces the settings of a stream. Settings may be written
+	// before the first append.
+	SetStreamSettings(ctx context.Context, entity Entity, settings StreamSettings) error
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -16,20 +17,35 @@ type causationContextKey struct{}
 const tracerName = "github.com/fgrzl/es"
 
 const (
-	spanRepositoryLoad      = "es.repository.load"
-	spanRepositorySave      = "es.repository.save"
-	spanRepositorySaveAudit = "es.repository.save_audit"
-
-	attributeEntityID          = "es.entity.id"
-	attributeEntityArea        = "es.entity.area"
-	attributeEntityScope       = "es.entity.scope"
-	attributeEntityTenantID    = "es.entity.tenant_id"
-	attributeCorrelationID     = "es.correlation_id"
-	attributeCausationID       = "es.causation_id"
-	attributeEventsCount       = "es.events.count"
-	attributePendingAuditCount = "es.pending_audits.count"
-	attributeSequenceExpected  = "es.sequence.expected"
-	attributeSequenceCurrent   = "es.sequence.current"
+	spanRepositoryLoad       = "es.repository.load"
+	spanRepositorySave       = "es.repository.save"
+	spanRepositorySaveAudit  = "es.repository.save_audit"
+	spanRepositoryExists     = "es.repository.exists"
+	spanRepositorySoftDelete = "es.repository.soft_delete"
+	spanRepositoryHardDelete = "es.repository.hard_delete"
+	spanRepositoryTruncate   = "es.repository.truncate"
+
+	spanStoreSaveEvents      = "es.store.save_events"
+	spanStoreLoadEvents      = "es.store.load_events"
+	spanStoreLoadEventStream = "es.store.load_event_stream"
+	spanStoreReadStream      = "es.store.read_stream"
+
+	spanProcessHandle = "es.process.handle"
+	spanCommandHandle = "es.command.handle"
+
+	attributeEntityID           = "es.entity.id"
+	attributeEntityArea         = "es.entity.area"
+	attributeEntityScope        = "es.entity.scope"
+	attributeEntityTenantID     = "es.entity.tenant_id"
+	attributeCorrelationID      = "es.correlation_id"
+	attributeCausationID        = "es.causation_id"
+	attributeEventsCount        = "es.events.count"
+	attributeEventDiscriminator = "es.event.discriminator"
+	attributeCommandType        = "es.command.type"
+	attributeCacheHit           = "es.cache.hit"
+	attributePendingAuditCount  = "es.pending_audits.count"
+	attributeSequenceExpected   = "es.sequence.expected"
+	attributeSequenceCurrent    = "es.sequence.current"
 )
 
 // ContextWithTracing adds correlation and causation IDs to the context.
@@ -66,6 +82,15 @@ func startSpan(ctx context.Context, name string, entity Entity, attrs ...attribu
 	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(spanAttrs...))
 }
 
+// recordSpanError marks the span as failed when err is non-nil and returns err.
+func recordSpanError(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
 func entityAttributes(entity Entity) []attribute.KeyValue {
 	attrs := []attribute.KeyValue{
 		attribute.String(attributeEntityID, entity.ID.String()),
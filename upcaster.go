@@ -0,0 +1,63 @@
+package es
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxUpcastSteps bounds an upcaster chain so a cycle fails instead of looping.
+const maxUpcastSteps = 32
+
+// Upcaster rewrites the envelope of an old event shape into a newer one, for
+// example renaming a payload field or the discriminator. Decoding applies the
+// upcaster registered for an envelope's discriminator, then any registered for
+// the discriminator it returns, until the discriminator stops changing.
+type Upcaster func(Envelope) (Envelope, error)
+
+var upcasterRegistry = struct {
+	sync.RWMutex
+	upcasters map[string]Upcaster
+}{upcasters: make(map[string]Upcaster)}
+
+// RegisterUpcaster registers the upcaster for envelopes with a discriminator.
+// An upcaster registered for a discriminator that is still in use also sees
+// envelopes already in the current shape and must return them unchanged.
+// Registering a second upcaster for a discriminator panics.
+func RegisterUpcaster(discriminator string, upcaster Upcaster) {
+	upcasterRegistry.Lock()
+	defer upcasterRegistry.Unlock()
+
+	if _, ok := upcasterRegistry.upcasters[discriminator]; ok {
+		panic(fmt.Sprintf("RegisterUpcaster: discriminator %s already has an upcaster", discriminator))
+	}
+	upcasterRegistry.upcasters[discriminator] = upcaster
+}
+
+// LookupUpcaster returns the upcaster registered for a discriminator.
+func LookupUpcaster(discriminator string) (Upcaster, bool) {
+	upcasterRegistry.RLock()
+	defer upcasterRegistry.RUnlock()
+	upcaster, ok := upcasterRegistry.upcasters[discriminator]
+	return upcaster, ok
+}
+
+// Upcast applies the registered upcasters to a decompressed envelope.
+func (e Envelope) Upcast() (Envelope, error) {
+	for range maxUpcastSteps {
+		upcaster, ok := LookupUpcaster(e.Discriminator)
+		if !ok {
+			return e, nil
+		}
+
+		from := e.Discriminator
+		next, err := upcaster(e)
+		if err != nil {
+			return Envelope{}, fmt.Errorf("upcast %s: %w", from, err)
+		}
+		e = next
+		if e.Discriminator == from {
+			return e, nil
+		}
+	}
+	return Envelope{}, fmt.Errorf("upcast %s: more than %d steps", e.Discriminator, maxUpcastSteps)
+}
@@ -0,0 +1,70 @@
+package es
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	// dummy_named was renamed to dummy_created, and its "Title" field to "Name".
+	RegisterUpcaster("dummy_named", func(e Envelope) (Envelope, error) {
+		var payload map[string]json.RawMessage
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return Envelope{}, err
+		}
+		payload["Name"] = payload["Title"]
+		delete(payload, "Title")
+
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return Envelope{}, err
+		}
+		e.Discriminator = "dummy_created"
+		e.Payload = data
+		return e, nil
+	})
+	RegisterUpcaster("dummy_looped_a", func(e Envelope) (Envelope, error) {
+		e.Discriminator = "dummy_looped_b"
+		return e, nil
+	})
+	RegisterUpcaster("dummy_looped_b", func(e Envelope) (Envelope, error) {
+		e.Discriminator = "dummy_looped_a"
+		return e, nil
+	})
+}
+
+func TestShouldUpcastRenamedEventWhenDecoding(t *testing.T) {
+	// Arrange
+	metadata := EventMetadata{Entity: NewEntity(uuid.New(), AreaDummy), EventID: uuid.New(), Sequence: 1}
+	envelope := Envelope{Discriminator: "dummy_named", Metadata: metadata, Payload: []byte(`{"Title":"old"}`)}
+
+	// Act
+	event, err := envelope.Event()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "old", event.(*DummyCreated).Name)
+	assert.Equal(t, metadata, event.GetMetadata())
+}
+
+func TestShouldFailOnUpcasterCycle(t *testing.T) {
+	// Arrange
+	envelope := Envelope{Discriminator: "dummy_looped_a", Payload: []byte(`{}`)}
+
+	// Act
+	_, err := envelope.Event()
+
+	// Assert
+	assert.ErrorContains(t, err, "more than 32 steps")
+}
+
+func TestShouldPanicWhenUpcasterRegisteredTwice(t *testing.T) {
+	// Act & Assert
+	assert.Panics(t, func() {
+		RegisterUpcaster("dummy_named", func(e Envelope) (Envelope, error) { return e, nil })
+	})
+}